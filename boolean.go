@@ -0,0 +1,295 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AndFilter matches tasks that match every one of its filters
+type AndFilter struct {
+	Filters []Filter
+}
+
+func (f *AndFilter) Matches(task *Task) bool {
+	for _, filter := range f.Filters {
+		if !filter.Matches(task) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OrFilter matches tasks that match at least one of its filters
+type OrFilter struct {
+	Filters []Filter
+}
+
+func (f *OrFilter) Matches(task *Task) bool {
+	for _, filter := range f.Filters {
+		if filter.Matches(task) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// XorFilter matches tasks that match exactly one of its two filters
+type XorFilter struct {
+	Left  Filter
+	Right Filter
+}
+
+func (f *XorFilter) Matches(task *Task) bool {
+	return f.Left.Matches(task) != f.Right.Matches(task)
+}
+
+// NotFilter inverts its filter
+type NotFilter struct {
+	Filter Filter
+}
+
+func (f *NotFilter) Matches(task *Task) bool {
+	return !f.Filter.Matches(task)
+}
+
+// isBooleanLine reports whether a query line uses the boolean combinator
+// syntax, i.e. starts with a delimited sub-filter or with NOT
+func isBooleanLine(line string) bool {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "NOT "))
+
+	return strings.HasPrefix(line, "(") || strings.HasPrefix(line, `"`)
+}
+
+type boolTokenKind int
+
+const (
+	boolTokenOperand boolTokenKind = iota
+	boolTokenAnd
+	boolTokenOr
+	boolTokenXor
+	boolTokenNot
+)
+
+type boolToken struct {
+	text string
+	kind boolTokenKind
+}
+
+// tokenizeBoolean splits a boolean query line into delimited operands and
+// operators. Operands are delimited by matching parentheses or by double
+// quotes.
+func tokenizeBoolean(line string) ([]boolToken, error) {
+	var tokens []boolToken
+
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			end, err := matchingParen(line, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, boolToken{kind: boolTokenOperand, text: line[i+1 : end]})
+			i = end + 1
+		case c == '"':
+			end := strings.IndexByte(line[i+1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated quote")
+			}
+
+			tokens = append(tokens, boolToken{kind: boolTokenOperand, text: line[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			end := strings.IndexAny(line[i:], " \t(\"")
+			if end < 0 {
+				end = len(line) - i
+			}
+
+			word := line[i : i+end]
+
+			kind, ok := boolOperator(word)
+			if !ok {
+				return nil, fmt.Errorf("unexpected %q: expected AND, OR, XOR, NOT or a delimited filter", word)
+			}
+
+			tokens = append(tokens, boolToken{kind: kind})
+			i += end
+		}
+	}
+
+	return tokens, nil
+}
+
+func boolOperator(word string) (boolTokenKind, bool) {
+	switch word {
+	case "AND":
+		return boolTokenAnd, true
+	case "OR":
+		return boolTokenOr, true
+	case "XOR":
+		return boolTokenXor, true
+	case "NOT":
+		return boolTokenNot, true
+	default:
+		return 0, false
+	}
+}
+
+// matchingParen returns the index of the parenthesis closing the one at start
+func matchingParen(line string, start int) (int, error) {
+	depth := 0
+
+	for i := start; i < len(line); i++ {
+		switch line[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+
+	return 0, errors.New("unbalanced parentheses")
+}
+
+// boolParser is a recursive-descent parser for boolean query lines. Operator
+// precedence, from loosest to tightest, is OR, XOR, AND, NOT.
+type boolParser struct {
+	parseOperand func(string) (Filter, error)
+	tokens       []boolToken
+	pos          int
+}
+
+// parseBooleanLine parses a line such as "(done) OR (tag include #work)" into
+// a tree of filters, using parseOperand for each delimited sub-filter
+func parseBooleanLine(line string, parseOperand func(string) (Filter, error)) (Filter, error) {
+	tokens, err := tokenizeBoolean(line)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &boolParser{tokens: tokens, parseOperand: parseOperand}
+
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, errors.New("unexpected trailing input after boolean expression")
+	}
+
+	return filter, nil
+}
+
+func (p *boolParser) accept(kind boolTokenKind) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *boolParser) parseOr() (Filter, error) {
+	left, err := p.parseXor()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []Filter{left}
+
+	for p.accept(boolTokenOr) {
+		right, err := p.parseXor()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, right)
+	}
+
+	if len(filters) == 1 {
+		return left, nil
+	}
+
+	return &OrFilter{Filters: filters}, nil
+}
+
+func (p *boolParser) parseXor() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(boolTokenXor) {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &XorFilter{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *boolParser) parseAnd() (Filter, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []Filter{left}
+
+	for p.accept(boolTokenAnd) {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, right)
+	}
+
+	if len(filters) == 1 {
+		return left, nil
+	}
+
+	return &AndFilter{Filters: filters}, nil
+}
+
+func (p *boolParser) parseNot() (Filter, error) {
+	if p.accept(boolTokenNot) {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &NotFilter{Filter: inner}, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end of boolean expression")
+	}
+
+	tok := p.tokens[p.pos]
+	if tok.kind != boolTokenOperand {
+		return nil, errors.New("expected a delimited filter before operator")
+	}
+
+	p.pos++
+
+	text := strings.TrimSpace(tok.text)
+	if text == "" {
+		return nil, errors.New("empty filter in boolean expression")
+	}
+
+	return p.parseOperand(text)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:funlen // comprehensive test cases
+func TestParseQueryBoolean(t *testing.T) {
+	work := &Task{Status: "incomplete", Tags: []string{"work"}, FilePath: "Inbox/todo.md"}
+	project := &Task{Status: "incomplete", Tags: []string{}, FilePath: "Projects/app.md"}
+	doneWork := &Task{Status: "complete", Tags: []string{"work"}, FilePath: "Projects/app.md"}
+	other := &Task{Status: "incomplete", Tags: []string{"home"}, FilePath: "Inbox/todo.md"}
+
+	tests := []struct {
+		want  map[*Task]bool
+		name  string
+		query string
+	}{
+		{
+			name:  "or",
+			query: "(tag include #work) OR (path includes Projects)",
+			want:  map[*Task]bool{work: true, project: true, doneWork: true, other: false},
+		},
+		{
+			name:  "and",
+			query: "(tag include #work) AND (path includes Projects)",
+			want:  map[*Task]bool{work: false, project: false, doneWork: true, other: false},
+		},
+		{
+			name:  "not",
+			query: "NOT (tag include #work)",
+			want:  map[*Task]bool{work: false, project: true, doneWork: false, other: true},
+		},
+		{
+			name:  "and not",
+			query: "(path includes Projects) AND NOT (done)",
+			want:  map[*Task]bool{work: false, project: true, doneWork: false, other: false},
+		},
+		{
+			name:  "xor",
+			query: "(tag include #work) XOR (path includes Projects)",
+			want:  map[*Task]bool{work: true, project: true, doneWork: false, other: false},
+		},
+		{
+			name:  "nested parentheses",
+			query: "((tag include #work) OR (tag include #home)) AND (path includes Inbox)",
+			want:  map[*Task]bool{work: true, project: false, doneWork: false, other: true},
+		},
+		{
+			name:  "and binds tighter than or",
+			query: "(done) OR (tag include #home) AND (path includes Inbox)",
+			want:  map[*Task]bool{work: false, project: false, doneWork: true, other: true},
+		},
+		{
+			name:  "quoted operands",
+			query: `"tag include #home" OR "done"`,
+			want:  map[*Task]bool{work: false, project: false, doneWork: true, other: true},
+		},
+		{
+			name:  "combined with other lines",
+			query: "not done\n(tag include #work) OR (tag include #home)",
+			want:  map[*Task]bool{work: true, project: false, doneWork: false, other: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			for task, want := range tt.want {
+				assert.Equal(t, want, q.Matches(task), "task %+v", task)
+			}
+		})
+	}
+}
+
+func TestParseQueryBooleanErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "unbalanced parentheses", query: "(done OR (not done)"},
+		{name: "unterminated quote", query: `"done OR (not done)`},
+		{name: "missing operand", query: "(done) AND"},
+		{name: "unknown operator", query: "(done) NAND (not done)"},
+		{name: "adjacent operands", query: "(done) (not done)"},
+		{name: "unknown sub-filter", query: "(done) OR (bogus filter)"},
+		{name: "empty operand", query: "(done) OR ()"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			require.Error(t, err)
+		})
+	}
+}
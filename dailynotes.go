@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultDailyNoteFormat = "YYYY-MM-DD"
+
+// momentTokenLetters are the letters moment.js reads as (parts of) format
+// tokens. Other letters are copied literally.
+const momentTokenLetters = "AaDdEeGgHhkLlMmNnoQSsWwXxYyZ"
+
+// dailyNotesSettings mirrors the core Daily notes plugin's settings in
+// .obsidian/daily-notes.json
+type dailyNotesSettings struct {
+	Folder string `json:"folder"`
+	Format string `json:"format"`
+}
+
+// dailyNotePath returns the path, relative to root, of the daily note for
+// day, honoring the Daily notes folder and date format settings of the vault
+// containing root. If the format can't be used, the note is named YYYY-MM-DD
+// instead, and warning says why.
+func dailyNotePath(root string, day time.Time) (relPath, warning string) {
+	var settings dailyNotesSettings
+
+	vaultDir := root
+
+	if configDir, ok := findConfigDir(root); ok {
+		vaultDir = filepath.Dir(configDir)
+
+		data, err := os.ReadFile(filepath.Join(configDir, "daily-notes.json"))
+		if err == nil {
+			// a malformed settings file just means we use the defaults
+			_ = json.Unmarshal(data, &settings)
+		}
+	}
+
+	format := settings.Format
+	if format == "" {
+		format = defaultDailyNoteFormat
+	}
+
+	name, err := formatMoment(format, day)
+	if err != nil {
+		name, _ = formatMoment(defaultDailyNoteFormat, day)
+		warning = fmt.Sprintf("the daily note format %q is not supported (%v), so the note was named %s instead",
+			format, err, defaultDailyNoteFormat)
+	}
+
+	// the folder is relative to the vault, which may be above root
+	path := filepath.Join(vaultDir, filepath.FromSlash(strings.Trim(settings.Folder, "/")), filepath.FromSlash(name+".md"))
+
+	relPath, err = filepath.Rel(root, path)
+	if err != nil {
+		relPath = path
+	}
+
+	return relPath, warning
+}
+
+// momentToken is a moment.js format token and how to render it
+type momentToken struct {
+	format func(t time.Time) string
+	token  string
+}
+
+// momentTokens lists the moment.js format tokens formatMoment understands,
+// longest first so that e.g. "MMMM" wins over "MM". Week numbers and
+// weekday names follow moment's default (English) locale, as Obsidian does
+// unless configured otherwise.
+func momentTokens() []momentToken {
+	isoWeek := func(t time.Time) int {
+		_, week := t.ISOWeek()
+
+		return week
+	}
+
+	return []momentToken{
+		{token: "YYYY", format: func(t time.Time) string { return t.Format("2006") }},
+		{token: "YY", format: func(t time.Time) string { return t.Format("06") }},
+		{token: "GGGG", format: func(t time.Time) string { year, _ := t.ISOWeek(); return strconv.Itoa(year) }},
+		{token: "gggg", format: func(t time.Time) string { year, _ := localeWeek(t); return strconv.Itoa(year) }},
+		{token: "Q", format: func(t time.Time) string { return strconv.Itoa((int(t.Month())-1)/3 + 1) }},
+		{token: "MMMM", format: func(t time.Time) string { return t.Format("January") }},
+		{token: "MMM", format: func(t time.Time) string { return t.Format("Jan") }},
+		{token: "MM", format: func(t time.Time) string { return t.Format("01") }},
+		{token: "Mo", format: func(t time.Time) string { return ordinal(int(t.Month())) }},
+		{token: "M", format: func(t time.Time) string { return strconv.Itoa(int(t.Month())) }},
+		{token: "DDDD", format: func(t time.Time) string { return fmt.Sprintf("%03d", t.YearDay()) }},
+		{token: "DDD", format: func(t time.Time) string { return strconv.Itoa(t.YearDay()) }},
+		{token: "DD", format: func(t time.Time) string { return t.Format("02") }},
+		{token: "Do", format: func(t time.Time) string { return ordinal(t.Day()) }},
+		{token: "D", format: func(t time.Time) string { return strconv.Itoa(t.Day()) }},
+		{token: "dddd", format: func(t time.Time) string { return t.Format("Monday") }},
+		{token: "ddd", format: func(t time.Time) string { return t.Format("Mon") }},
+		{token: "dd", format: func(t time.Time) string { return t.Format("Mon")[:2] }},
+		{token: "d", format: func(t time.Time) string { return strconv.Itoa(int(t.Weekday())) }},
+		{token: "E", format: func(t time.Time) string { return strconv.Itoa((int(t.Weekday())+6)%7 + 1) }},
+		{token: "WW", format: func(t time.Time) string { return fmt.Sprintf("%02d", isoWeek(t)) }},
+		{token: "Wo", format: func(t time.Time) string { return ordinal(isoWeek(t)) }},
+		{token: "W", format: func(t time.Time) string { return strconv.Itoa(isoWeek(t)) }},
+		{token: "ww", format: func(t time.Time) string { _, week := localeWeek(t); return fmt.Sprintf("%02d", week) }},
+		{token: "wo", format: func(t time.Time) string { _, week := localeWeek(t); return ordinal(week) }},
+		{token: "w", format: func(t time.Time) string { _, week := localeWeek(t); return strconv.Itoa(week) }},
+	}
+}
+
+// formatMoment formats t using a moment.js format string, as Obsidian does
+// for daily note names. Text in square brackets is copied literally. It
+// returns an error for tokens it doesn't support, such as times of day.
+func formatMoment(format string, t time.Time) (string, error) {
+	var b strings.Builder
+
+	tokens := momentTokens()
+
+	for i := 0; i < len(format); {
+		if format[i] == '[' {
+			if end := strings.IndexByte(format[i:], ']'); end > 0 {
+				b.WriteString(format[i+1 : i+end])
+				i += end + 1
+
+				continue
+			}
+		}
+
+		var token *momentToken
+
+		for j := range tokens {
+			if strings.HasPrefix(format[i:], tokens[j].token) {
+				token = &tokens[j]
+
+				break
+			}
+		}
+
+		if token == nil {
+			if strings.IndexByte(momentTokenLetters, format[i]) >= 0 {
+				return "", fmt.Errorf("unsupported token %q", tokenAt(format, i))
+			}
+
+			b.WriteByte(format[i])
+			i++
+
+			continue
+		}
+
+		b.WriteString(token.format(t))
+		i += len(token.token)
+	}
+
+	return b.String(), nil
+}
+
+// tokenAt returns the run of repeated letters at format[i], e.g. "HH"
+func tokenAt(format string, i int) string {
+	end := i + 1
+	for end < len(format) && format[end] == format[i] {
+		end++
+	}
+
+	return format[i:end]
+}
+
+// localeWeek returns the week-numbering year and week of t in moment's
+// default locale: weeks start on Sunday, and week 1 is the week containing
+// January 1st
+func localeWeek(t time.Time) (int, int) {
+	// a week belongs to the year its Saturday is in
+	year := t.AddDate(0, 0, 6-int(t.Weekday())).Year()
+
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	firstWeek := jan1.AddDate(0, 0, -int(jan1.Weekday()))
+	weekStart := time.Date(t.Year(), t.Month(), t.Day()-int(t.Weekday()), 0, 0, 0, 0, t.Location())
+
+	return year, int(weekStart.Sub(firstWeek).Hours()/24)/7 + 1
+}
+
+// ordinal returns n with its English ordinal suffix, e.g. "1st" or "12th"
+func ordinal(n int) string {
+	suffix := "th"
+
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+
+	return strconv.Itoa(n) + suffix
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatMoment(t *testing.T) {
+	day := time.Date(2026, time.October, 6, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "YYYY-MM-DD", want: "2026-10-06"},
+		{format: "YY.M.D", want: "26.10.6"},
+		{format: "dddd, MMMM D", want: "Tuesday, October 6"},
+		{format: "ddd DD MMM YYYY", want: "Tue 06 Oct 2026"},
+		{format: "[Week of] YYYY-MM-DD", want: "Week of 2026-10-06"},
+		{format: "YYYY/MM/YYYY-MM-DD", want: "2026/10/2026-10-06"},
+		{format: "MMMM Do, YYYY", want: "October 6th, 2026"},
+		{format: "GGGG-[W]WW-E", want: "2026-W41-2"},
+		{format: "gggg-[w]ww-d dd", want: "2026-w41-2 Tu"},
+		{format: "YYYY-[Q]Q DDDD", want: "2026-Q4 279"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := formatMoment(tt.format, day)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := formatMoment("YYYY-MM-DD HH:mm", day)
+	require.ErrorContains(t, err, `"HH"`)
+}
+
+func TestLocaleWeek(t *testing.T) {
+	tests := []struct {
+		day  time.Time
+		year int
+		week int
+	}{
+		// Thursday January 1st
+		{day: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), year: 2026, week: 1},
+		{day: time.Date(2026, time.January, 4, 0, 0, 0, 0, time.UTC), year: 2026, week: 2},
+		// the Sunday of the week containing January 1st 2027 (a Friday)
+		{day: time.Date(2026, time.December, 27, 0, 0, 0, 0, time.UTC), year: 2027, week: 1},
+		{day: time.Date(2026, time.December, 26, 0, 0, 0, 0, time.UTC), year: 2026, week: 52},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.day.Format(dateLayout), func(t *testing.T) {
+			year, week := localeWeek(tt.day)
+			assert.Equal(t, tt.year, year)
+			assert.Equal(t, tt.week, week)
+		})
+	}
+}
+
+func TestDailyNotePath(t *testing.T) {
+	day := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	root := t.TempDir()
+
+	relPath, warning := dailyNotePath(root, day)
+	assert.Equal(t, "2026-10-16.md", relPath)
+	assert.Empty(t, warning)
+
+	writeDailyNotesSettings(t, root, `{"folder":"Journal/Daily","format":"DD-MM-YYYY"}`)
+
+	relPath, warning = dailyNotePath(root, day)
+	assert.Equal(t, filepath.Join("Journal", "Daily", "16-10-2026.md"), relPath)
+	assert.Empty(t, warning)
+
+	// the folder is relative to the vault, not to a root inside it
+	subRoot := filepath.Join(root, "Journal")
+	require.NoError(t, os.MkdirAll(subRoot, 0o755))
+
+	relPath, warning = dailyNotePath(subRoot, day)
+	assert.Equal(t, filepath.Join("Daily", "16-10-2026.md"), relPath)
+	assert.Empty(t, warning)
+
+	writeDailyNotesSettings(t, root, `{"format":"YYYY-MM-DD HH.mm"}`)
+
+	relPath, warning = dailyNotePath(root, day)
+	assert.Equal(t, "2026-10-16.md", relPath)
+	assert.Contains(t, warning, `"YYYY-MM-DD HH.mm"`)
+}
+
+func TestCreateDailyTaskWarning(t *testing.T) {
+	root := t.TempDir()
+	writeDailyNotesSettings(t, root, `{"format":"YYYY-MM-DD-hh"}`)
+
+	s := testServer(t, root)
+
+	result, out, err := s.createTask(t.Context(), nil, CreateTaskInput{Description: "Call Bob", Daily: true})
+	require.NoError(t, err)
+	require.Nil(t, result)
+	assert.Equal(t, "2026-10-16.md", out.Task.FilePath)
+	require.Len(t, out.Warnings, 1)
+	assert.Contains(t, out.Warnings[0], `"hh"`)
+}
+
+func writeDailyNotesSettings(t *testing.T, root, settings string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".obsidian"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".obsidian", "daily-notes.json"), []byte(settings), 0o600))
+}
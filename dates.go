@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// DateRange is an inclusive range of calendar days in YYYY-MM-DD form. Start
+// and End are equal for a single day.
+type DateRange struct {
+	Start string
+	End   string
+}
+
+var (
+	isoDateRegex      = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	isoDateRangeRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})\s+(\d{4}-\d{2}-\d{2})$`)
+	isoWeekRegex      = regexp.MustCompile(`^(\d{4})-w(\d{2})$`)
+	isoMonthRegex     = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+	isoQuarterRegex   = regexp.MustCompile(`^(\d{4})-q([1-4])$`)
+	isoYearRegex      = regexp.MustCompile(`^(\d{4})$`)
+	relativeDayRegex  = regexp.MustCompile(`^(?:(this|next|last) )?(monday|tuesday|wednesday|thursday|friday|saturday|sunday)$`)
+	relativeSpanRegex = regexp.MustCompile(`^(this|next|last) (week|month|quarter|year)$`)
+)
+
+// today returns the current calendar day in loc, as midnight UTC so that
+// day arithmetic is unaffected by DST transitions
+func today(now func() time.Time, loc *time.Location) time.Time {
+	if now == nil {
+		now = time.Now
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+
+	y, m, d := now().In(loc).Date()
+
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func dayRange(start, end time.Time) DateRange {
+	return DateRange{Start: start.Format(dateLayout), End: end.Format(dateLayout)}
+}
+
+// parseDateRange resolves a date expression relative to today. It accepts
+// literal dates ("2026-10-01"), ranges ("2026-10-01 2026-10-31"), ISO weeks
+// ("2026-W42"), months ("2026-10"), quarters ("2026-Q4") and years ("2026"),
+// plus the relative forms "today", "tomorrow", "yesterday", weekdays
+// ("monday", "next friday", "last sunday") and "this/next/last
+// week/month/quarter/year". Weeks start on Monday.
+//
+//nolint:gocyclo,funlen // one branch per supported date form
+func parseDateRange(expr string, today time.Time) (DateRange, error) {
+	expr = strings.ToLower(strings.Join(strings.Fields(expr), " "))
+
+	switch expr {
+	case "today":
+		return dayRange(today, today), nil
+	case "tomorrow":
+		d := today.AddDate(0, 0, 1)
+
+		return dayRange(d, d), nil
+	case "yesterday":
+		d := today.AddDate(0, 0, -1)
+
+		return dayRange(d, d), nil
+	}
+
+	if isoDateRegex.MatchString(expr) {
+		d, err := time.Parse(dateLayout, expr)
+		if err != nil {
+			return DateRange{}, fmt.Errorf("invalid date %q: %w", expr, err)
+		}
+
+		return dayRange(d, d), nil
+	}
+
+	if matches := isoDateRangeRegex.FindStringSubmatch(expr); len(matches) >= 3 {
+		start, err := time.Parse(dateLayout, matches[1])
+		if err != nil {
+			return DateRange{}, fmt.Errorf("invalid date %q: %w", matches[1], err)
+		}
+
+		end, err := time.Parse(dateLayout, matches[2])
+		if err != nil {
+			return DateRange{}, fmt.Errorf("invalid date %q: %w", matches[2], err)
+		}
+
+		if end.Before(start) {
+			start, end = end, start
+		}
+
+		return dayRange(start, end), nil
+	}
+
+	if matches := isoWeekRegex.FindStringSubmatch(expr); len(matches) >= 3 {
+		year, _ := strconv.Atoi(matches[1])
+		week, _ := strconv.Atoi(matches[2])
+
+		start := isoWeekStart(year, week)
+		if _, w := start.ISOWeek(); w != week {
+			return DateRange{}, fmt.Errorf("invalid ISO week %q", expr)
+		}
+
+		return dayRange(start, start.AddDate(0, 0, 6)), nil
+	}
+
+	if matches := isoMonthRegex.FindStringSubmatch(expr); len(matches) >= 3 {
+		year, _ := strconv.Atoi(matches[1])
+		month, _ := strconv.Atoi(matches[2])
+
+		if month < 1 || month > 12 {
+			return DateRange{}, fmt.Errorf("invalid month %q", expr)
+		}
+
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+
+		return dayRange(start, start.AddDate(0, 1, -1)), nil
+	}
+
+	if matches := isoQuarterRegex.FindStringSubmatch(expr); len(matches) >= 3 {
+		year, _ := strconv.Atoi(matches[1])
+		quarter, _ := strconv.Atoi(matches[2])
+
+		start := time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+
+		return dayRange(start, start.AddDate(0, 3, -1)), nil
+	}
+
+	if matches := isoYearRegex.FindStringSubmatch(expr); len(matches) >= 2 {
+		year, _ := strconv.Atoi(matches[1])
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		return dayRange(start, start.AddDate(1, 0, -1)), nil
+	}
+
+	if matches := relativeDayRegex.FindStringSubmatch(expr); len(matches) >= 3 {
+		d := relativeWeekday(today, matches[1], parseWeekday(matches[2]))
+
+		return dayRange(d, d), nil
+	}
+
+	if matches := relativeSpanRegex.FindStringSubmatch(expr); len(matches) >= 3 {
+		return relativeSpan(today, matches[1], matches[2]), nil
+	}
+
+	return DateRange{}, fmt.Errorf("unrecognized date %q", expr)
+}
+
+func parseWeekday(name string) time.Weekday {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d
+		}
+	}
+
+	return time.Sunday
+}
+
+// relativeWeekday resolves "monday" (today or the next Monday), "next monday"
+// (the first Monday after today), "last monday" (the most recent Monday before
+// today) and "this monday" (the Monday of the current week)
+func relativeWeekday(today time.Time, modifier string, day time.Weekday) time.Time {
+	diff := int(day - today.Weekday())
+
+	switch modifier {
+	case "next":
+		if diff <= 0 {
+			diff += 7
+		}
+	case "last":
+		if diff >= 0 {
+			diff -= 7
+		}
+	case "this":
+		return weekStart(today).AddDate(0, 0, (int(day)+6)%7)
+	default:
+		if diff < 0 {
+			diff += 7
+		}
+	}
+
+	return today.AddDate(0, 0, diff)
+}
+
+// relativeSpan resolves "this week", "next month", "last quarter" and so on
+func relativeSpan(today time.Time, modifier, unit string) DateRange {
+	offset := 0
+
+	switch modifier {
+	case "next":
+		offset = 1
+	case "last":
+		offset = -1
+	}
+
+	var start, end time.Time
+
+	switch unit {
+	case "week":
+		start = weekStart(today).AddDate(0, 0, 7*offset)
+		end = start.AddDate(0, 0, 6)
+	case "month":
+		start = time.Date(today.Year(), today.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, -1)
+	case "quarter":
+		first := time.Month((int(today.Month())-1)/3*3 + 1)
+		start = time.Date(today.Year(), first+time.Month(3*offset), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 3, -1)
+	default:
+		start = time.Date(today.Year()+offset, time.January, 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(1, 0, -1)
+	}
+
+	return dayRange(start, end)
+}
+
+// weekStart returns the Monday on or before d
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+// isoWeekStart returns the Monday of the given ISO week
+func isoWeekStart(year, week int) time.Time {
+	// January 4th is always in week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+
+	return weekStart(jan4).AddDate(0, 0, 7*(week-1))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:funlen // comprehensive test cases
+func TestParseDateRange(t *testing.T) {
+	// Friday
+	now := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    DateRange
+		wantErr bool
+	}{
+		{name: "literal date", expr: "2026-10-01", want: DateRange{"2026-10-01", "2026-10-01"}},
+		{name: "literal range", expr: "2026-10-01 2026-10-31", want: DateRange{"2026-10-01", "2026-10-31"}},
+		{name: "reversed range", expr: "2026-10-31 2026-10-01", want: DateRange{"2026-10-01", "2026-10-31"}},
+		{name: "today", expr: "today", want: DateRange{"2026-10-16", "2026-10-16"}},
+		{name: "tomorrow", expr: "Tomorrow", want: DateRange{"2026-10-17", "2026-10-17"}},
+		{name: "yesterday", expr: "yesterday", want: DateRange{"2026-10-15", "2026-10-15"}},
+		{name: "bare weekday today", expr: "friday", want: DateRange{"2026-10-16", "2026-10-16"}},
+		{name: "bare weekday upcoming", expr: "monday", want: DateRange{"2026-10-19", "2026-10-19"}},
+		{name: "next weekday", expr: "next monday", want: DateRange{"2026-10-19", "2026-10-19"}},
+		{name: "next same weekday", expr: "next friday", want: DateRange{"2026-10-23", "2026-10-23"}},
+		{name: "last weekday", expr: "last friday", want: DateRange{"2026-10-09", "2026-10-09"}},
+		{name: "this weekday", expr: "this monday", want: DateRange{"2026-10-12", "2026-10-12"}},
+		{name: "this week", expr: "this week", want: DateRange{"2026-10-12", "2026-10-18"}},
+		{name: "next week", expr: "next week", want: DateRange{"2026-10-19", "2026-10-25"}},
+		{name: "last month", expr: "last month", want: DateRange{"2026-09-01", "2026-09-30"}},
+		{name: "next quarter", expr: "next quarter", want: DateRange{"2027-01-01", "2027-03-31"}},
+		{name: "this year", expr: "this year", want: DateRange{"2026-01-01", "2026-12-31"}},
+		{name: "iso week", expr: "2026-W42", want: DateRange{"2026-10-12", "2026-10-18"}},
+		{name: "iso week 1 in previous year", expr: "2026-W01", want: DateRange{"2025-12-29", "2026-01-04"}},
+		{name: "iso month", expr: "2026-02", want: DateRange{"2026-02-01", "2026-02-28"}},
+		{name: "iso quarter", expr: "2026-Q4", want: DateRange{"2026-10-01", "2026-12-31"}},
+		{name: "iso year", expr: "2027", want: DateRange{"2027-01-01", "2027-12-31"}},
+		{name: "invalid date", expr: "2026-02-30", wantErr: true},
+		{name: "invalid week", expr: "2026-W54", wantErr: true},
+		{name: "invalid month", expr: "2026-13", wantErr: true},
+		{name: "nonsense", expr: "someday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDateRange(tt.expr, now)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestToday(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 02:00 UTC is still the previous evening in New York
+	now := func() time.Time { return time.Date(2026, time.October, 16, 2, 0, 0, 0, time.UTC) }
+
+	assert.Equal(t, "2026-10-15", today(now, loc).Format(dateLayout))
+	assert.Equal(t, "2026-10-16", today(now, time.UTC).Format(dateLayout))
+}
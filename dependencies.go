@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// linkDependencies sets Blocked and Blocking on each task from the graph of
+// 🆔 ids and ⛔ dependencies across all of tasks, and returns the dependency
+// cycles found, each as the (sorted) ids of the tasks in it.
+//
+// As in the Tasks plugin, a task is blocked if it is not done and depends on
+// a task that is not done, and blocking if it is not done and a task that is
+// not done depends on it. Dependencies on unknown ids are ignored.
+func linkDependencies(tasks []*Task) [][]string {
+	byDependencyID := make(map[string][]*Task)
+
+	for _, task := range tasks {
+		task.Blocked, task.Blocking = false, false
+
+		if task.DependencyID != "" {
+			byDependencyID[task.DependencyID] = append(byDependencyID[task.DependencyID], task)
+		}
+	}
+
+	for _, task := range tasks {
+		if task.StatusType.IsDone() {
+			continue
+		}
+
+		for _, dep := range dependenciesOf(task, byDependencyID) {
+			if !dep.StatusType.IsDone() {
+				task.Blocked = true
+				dep.Blocking = true
+			}
+		}
+	}
+
+	return dependencyCycles(tasks, byDependencyID)
+}
+
+// dependenciesOf returns the tasks that task depends on
+func dependenciesOf(task *Task, byDependencyID map[string][]*Task) []*Task {
+	var deps []*Task
+
+	for _, id := range task.DependsOn {
+		deps = append(deps, byDependencyID[id]...)
+	}
+
+	return deps
+}
+
+// dependencyCycles finds the strongly connected components of the
+// dependency graph (using Tarjan's algorithm) that contain a cycle
+func dependencyCycles(tasks []*Task, byDependencyID map[string][]*Task) [][]string {
+	type state struct {
+		index, lowLink int
+		onStack        bool
+	}
+
+	var (
+		cycles [][]string
+		stack  []*Task
+		next   int
+		visit  func(task *Task)
+	)
+
+	states := make(map[*Task]*state, len(tasks))
+
+	visit = func(task *Task) {
+		st := &state{index: next, lowLink: next, onStack: true}
+		states[task] = st
+		next++
+
+		stack = append(stack, task)
+
+		selfLoop := false
+
+		for _, dep := range dependenciesOf(task, byDependencyID) {
+			if dep == task {
+				selfLoop = true
+			}
+
+			depState, seen := states[dep]
+
+			switch {
+			case !seen:
+				visit(dep)
+				st.lowLink = min(st.lowLink, states[dep].lowLink)
+			case depState.onStack:
+				st.lowLink = min(st.lowLink, depState.index)
+			}
+		}
+
+		if st.lowLink != st.index {
+			return
+		}
+
+		var component []string
+
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			states[top].onStack = false
+
+			component = append(component, top.ID)
+
+			if top == task {
+				break
+			}
+		}
+
+		if len(component) > 1 || selfLoop {
+			slices.Sort(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, task := range tasks {
+		if _, seen := states[task]; !seen {
+			visit(task)
+		}
+	}
+
+	slices.SortFunc(cycles, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+
+	return cycles
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkDependencies(t *testing.T) {
+	tasks := parseNested(`- [ ] Write report 🆔 report
+- [ ] Send report ⛔ report
+- [x] Book room 🆔 room
+- [ ] Hold meeting ⛔ room,report
+- [x] Done but waiting ⛔ report
+- [ ] Waiting on nothing ⛔ missing`)
+
+	cycles := linkDependencies(tasks)
+	assert.Empty(t, cycles)
+
+	//nolint:govet // test struct field alignment not critical
+	want := []struct {
+		blocked, blocking bool
+	}{
+		{false, true},
+		{true, false},
+		{false, false},
+		{true, false},
+		{false, false},
+		{false, false},
+	}
+
+	require.Len(t, tasks, len(want))
+
+	for i, w := range want {
+		assert.Equal(t, w.blocked, tasks[i].Blocked, "blocked: %s", tasks[i].Description)
+		assert.Equal(t, w.blocking, tasks[i].Blocking, "blocking: %s", tasks[i].Description)
+	}
+}
+
+func TestDependencyCycles(t *testing.T) {
+	tasks := parseNested(`- [ ] A 🆔 a ⛔ c
+- [ ] B 🆔 b ⛔ a
+- [ ] C 🆔 c ⛔ b
+- [ ] D 🆔 d ⛔ a
+- [ ] E 🆔 e ⛔ e`)
+
+	cycles := linkDependencies(tasks)
+	assert.Equal(t, [][]string{
+		{"todo.md:1", "todo.md:2", "todo.md:3"},
+		{"todo.md:5"},
+	}, cycles)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// excludeRules decides which files and folders a scan of one root skips:
+// hidden and system folders, the vault's "Excluded files" setting, and
+// globs given by the user
+type excludeRules struct {
+	root string
+	// vaultDir is the folder of the vault containing root, which Obsidian's
+	// excluded files are relative to
+	vaultDir string
+	// ignorePaths and ignorePatterns are the vault's excluded files: path
+	// prefixes such as "Templates/", and regular expressions
+	ignorePaths    []string
+	ignorePatterns []*regexp.Regexp
+	globs          []string
+}
+
+// obsidianAppSettings is the subset of Obsidian's .obsidian/app.json we read
+type obsidianAppSettings struct {
+	UserIgnoreFilters []string `json:"userIgnoreFilters"`
+}
+
+// validateGlobs reports the first malformed exclude glob
+func validateGlobs(globs []string) error {
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", glob, err)
+		}
+	}
+
+	return nil
+}
+
+// newExcludeRules returns the exclude rules for scanning root, reading the
+// excluded files setting of the vault containing it
+func newExcludeRules(root string, globs []string) (*excludeRules, error) {
+	if err := validateGlobs(globs); err != nil {
+		return nil, err
+	}
+
+	rules := &excludeRules{root: root, globs: globs}
+
+	configDir, ok := findConfigDir(root)
+	if !ok {
+		return rules, nil
+	}
+
+	rules.vaultDir = filepath.Dir(configDir)
+
+	appPath := filepath.Join(configDir, "app.json")
+
+	data, err := os.ReadFile(appPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return rules, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Obsidian settings: %w", err)
+	}
+
+	var app obsidianAppSettings
+	if err := json.Unmarshal(data, &app); err != nil {
+		return nil, fmt.Errorf("invalid Obsidian settings in %q: %w", appPath, err)
+	}
+
+	for _, filter := range app.UserIgnoreFilters {
+		filter = strings.TrimSpace(filter)
+
+		switch {
+		case filter == "":
+			continue
+		case strings.HasPrefix(filter, "/") && len(filter) > 1:
+			re, err := compileRegexLiteral(filter)
+			if err != nil {
+				return nil, fmt.Errorf("invalid excluded files filter in %q: %w", appPath, err)
+			}
+
+			rules.ignorePatterns = append(rules.ignorePatterns, re)
+		default:
+			rules.ignorePaths = append(rules.ignorePaths, filter)
+		}
+	}
+
+	return rules, nil
+}
+
+// skip reports whether the scan skips the file or folder at file
+func (e *excludeRules) skip(file string, isDir bool) bool {
+	if file == e.root {
+		return false
+	}
+
+	name := filepath.Base(file)
+	if strings.HasPrefix(name, ".") || (isDir && name == "node_modules") {
+		return true
+	}
+
+	if e.vaultDir != "" && e.ignoredByVault(file, isDir) {
+		return true
+	}
+
+	rel, err := filepath.Rel(e.root, file)
+	if err != nil {
+		return false
+	}
+
+	rel = filepath.ToSlash(rel)
+
+	for _, glob := range e.globs {
+		pattern := strings.TrimSuffix(glob, "/")
+
+		if m, _ := path.Match(pattern, rel); m {
+			return true
+		}
+
+		// a glob without a slash matches names at any depth, like .gitignore
+		if !strings.Contains(pattern, "/") {
+			if m, _ := path.Match(pattern, name); m {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// excludes reports whether file, or any folder between the root and it, is
+// skipped
+func (e *excludeRules) excludes(file string) bool {
+	for p := file; p != e.root && isWithin(e.root, p); p = filepath.Dir(p) {
+		if e.skip(p, p != file) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ignoredByVault reports whether Obsidian's excluded files setting covers
+// file. Like Obsidian, path filters match by prefix: "Archive" excludes
+// "Archive/", "Archive.md" and "Archived/", while "Archive/" excludes only
+// the folder.
+func (e *excludeRules) ignoredByVault(file string, isDir bool) bool {
+	rel, err := filepath.Rel(e.vaultDir, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	rel = filepath.ToSlash(rel)
+	if isDir {
+		rel += "/"
+	}
+
+	for _, prefix := range e.ignorePaths {
+		if strings.HasPrefix(rel, prefix) {
+			return true
+		}
+	}
+
+	for _, re := range e.ignorePatterns {
+		if re.MatchString(rel) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanVaultExcludes(t *testing.T) {
+	vault := t.TempDir()
+
+	for _, file := range []string{
+		"todo.md",
+		"Projects/work.md",
+		"Projects/old.excalidraw.md",
+		"Archive/2024.md",
+		"Archived.md",
+		"Templates/daily.md",
+		"Templates.md",
+		"Drafts/scratch.md",
+		"notes/Drafts/other.md",
+		".obsidian/snippets/example.md",
+		".trash/deleted.md",
+		".git/notes.md",
+		"node_modules/pkg/README.md",
+		".hidden.md",
+	} {
+		path := filepath.Join(vault, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("- [ ] Task in "+file+"\n"), 0o600))
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(vault, ".obsidian", "app.json"),
+		[]byte(`{"userIgnoreFilters": ["Archive", "Templates/", "/^notes\\/.*\\/other/"]}`), 0o600))
+
+	scanned := func(t *testing.T, roots []string, exclude ...string) []string {
+		t.Helper()
+
+		result, err := ScanVaultWithOptions(t.Context(), roots, nil, ScanOptions{Exclude: exclude})
+		require.NoError(t, err)
+
+		paths := make([]string, 0, len(result.Tasks))
+		for _, task := range result.Tasks {
+			paths = append(paths, filepath.ToSlash(task.FilePath))
+		}
+
+		return paths
+	}
+
+	assert.Equal(t, []string{
+		"Drafts/scratch.md",
+		"Projects/old.excalidraw.md",
+		"Projects/work.md",
+		"Templates.md",
+		"todo.md",
+	}, scanned(t, []string{vault}))
+
+	assert.Equal(t, []string{
+		"Projects/work.md",
+		"Templates.md",
+		"todo.md",
+	}, scanned(t, []string{vault}, "Drafts", "*.excalidraw.md"))
+
+	assert.Equal(t, []string{"Templates.md", "todo.md"}, scanned(t, []string{vault}, "Projects/", "Drafts/*"))
+
+	// excluded files are relative to the vault even when scanning a folder
+	// inside it, while globs are relative to the scanned folder
+	assert.Equal(t, []string{"work.md"}, scanned(t, []string{filepath.Join(vault, "Projects")}, "old.*"))
+
+	_, err := ScanVaultWithOptions(t.Context(), []string{vault}, nil, ScanOptions{Exclude: []string{"[abc"}})
+	require.Error(t, err)
+}
+
+func TestScanVaultInvalidAppSettings(t *testing.T) {
+	vault := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(vault, ".obsidian"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(vault, ".obsidian", "app.json"),
+		[]byte(`{"userIgnoreFilters": ["/(/"]}`), 0o600))
+
+	_, err := ScanVault([]string{vault}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid excluded files filter")
+}
+
+func TestQueryTasksExclude(t *testing.T) {
+	vault := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(vault, "Archive"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "todo.md"), []byte("- [ ] Current\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "Archive", "old.md"), []byte("- [ ] Old\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "later.md"), []byte("- [ ] Later\n"), 0o600))
+
+	s := testServer(t, vault)
+	s.exclude = []string{"Archive"}
+
+	res, out, err := s.queryTasks(t.Context(), nil, QueryTasksInput{Exclude: []string{"later.md"}})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.Len(t, out.Tasks, 1)
+	assert.Equal(t, "Current", out.Tasks[0].Description)
+
+	res, _, err = s.queryTasks(t.Context(), nil, QueryTasksInput{Exclude: []string{"[later"}})
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.True(t, res.IsError)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"cmp"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// GroupField represents a field to group results by
+type GroupField int
+
+const (
+	GroupByFolder GroupField = iota
+	GroupByFilename
+	GroupByHeading
+	GroupByTags
+	GroupByDue
+	GroupByPriority
+	GroupByStatus
+	GroupByHappens
+)
+
+// GroupKey represents a "group by" instruction
+type GroupKey struct {
+	Field   GroupField
+	Reverse bool
+}
+
+// groupFields maps the field names accepted by "group by" to group fields
+func groupFields() map[string]GroupField {
+	return map[string]GroupField{
+		"folder":   GroupByFolder,
+		"filename": GroupByFilename,
+		"heading":  GroupByHeading,
+		"tags":     GroupByTags,
+		"due":      GroupByDue,
+		"priority": GroupByPriority,
+		"status":   GroupByStatus,
+		"happens":  GroupByHappens,
+	}
+}
+
+// TaskGroup is a group of tasks sharing a value of a "group by" field. With
+// several "group by" instructions, groups hold subgroups for the next field,
+// and only the innermost groups hold tasks.
+type TaskGroup struct {
+	Name   string       `json:"name"`
+	Groups []*TaskGroup `json:"groups,omitempty"`
+	Tasks  []*Task      `json:"tasks,omitempty"`
+	// Count is the number of tasks in the group, before "limit groups to"
+	Count int `json:"count"`
+}
+
+// taskGroupSchemas describes TaskGroup for schema inference, which cannot
+// follow recursive types. Nested subgroups are described as plain objects.
+func taskGroupSchemas() (map[reflect.Type]*jsonschema.Schema, error) {
+	taskSchema, err := jsonschema.For[Task](nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[reflect.Type]*jsonschema.Schema{
+		reflect.TypeFor[TaskGroup](): {
+			Type:     "object",
+			Required: []string{"name", "count"},
+			Properties: map[string]*jsonschema.Schema{
+				"name":   {Type: "string"},
+				"groups": {Type: "array", Items: &jsonschema.Schema{Type: "object"}},
+				"tasks":  {Type: "array", Items: taskSchema},
+				"count":  {Type: "integer"},
+			},
+		},
+	}, nil
+}
+
+// groupName is the name of a group together with the value groups are
+// ordered by
+type groupName struct {
+	name  string
+	order string
+}
+
+// noValueOrder sorts groups for tasks without a value (such as "No due
+// date") after all others
+const noValueOrder = "\uffff"
+
+// groupTasks groups tasks by each key in turn, keeping the tasks' order
+// within each group. limit, if positive, caps the number of tasks listed in
+// each innermost group.
+func groupTasks(tasks []*Task, keys []GroupKey, limit int) []*TaskGroup {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	key := keys[0]
+
+	var names []groupName
+
+	buckets := make(map[string][]*Task)
+
+	for _, task := range tasks {
+		for _, gn := range groupNamesFor(key.Field, task) {
+			if _, ok := buckets[gn.name]; !ok {
+				names = append(names, gn)
+			}
+
+			buckets[gn.name] = append(buckets[gn.name], task)
+		}
+	}
+
+	slices.SortStableFunc(names, func(a, b groupName) int {
+		c := cmp.Or(cmp.Compare(a.order, b.order), cmp.Compare(a.name, b.name))
+		if key.Reverse {
+			return -c
+		}
+
+		return c
+	})
+
+	groups := make([]*TaskGroup, 0, len(names))
+
+	for _, gn := range names {
+		bucket := buckets[gn.name]
+		group := &TaskGroup{Name: gn.name, Count: len(bucket)}
+
+		if len(keys) > 1 {
+			group.Groups = groupTasks(bucket, keys[1:], limit)
+		} else {
+			if limit > 0 && len(bucket) > limit {
+				bucket = bucket[:limit]
+			}
+
+			group.Tasks = bucket
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// groupNamesFor returns the groups a task belongs to for the given field.
+// Only grouping by tags can put a task in more than one group.
+//
+//nolint:gocyclo // one case per group field
+func groupNamesFor(field GroupField, task *Task) []groupName {
+	switch field {
+	case GroupByFolder:
+		folder := filepath.ToSlash(filepath.Dir(task.FilePath))
+		if folder == "." {
+			folder = ""
+		}
+
+		return []groupName{{name: folder + "/"}}
+	case GroupByFilename:
+		return []groupName{{name: strings.TrimSuffix(filepath.Base(task.FilePath), filepath.Ext(task.FilePath))}}
+	case GroupByHeading:
+		if task.Heading == "" {
+			return []groupName{{name: "(No heading)", order: noValueOrder}}
+		}
+
+		return []groupName{{name: task.Heading}}
+	case GroupByTags:
+		if len(task.Tags) == 0 {
+			return []groupName{{name: "(No tags)", order: noValueOrder}}
+		}
+
+		names := make([]groupName, 0, len(task.Tags))
+		for _, tag := range task.Tags {
+			if !slices.ContainsFunc(names, func(gn groupName) bool { return gn.name == "#"+tag }) {
+				names = append(names, groupName{name: "#" + tag, order: strings.ToLower(tag)})
+			}
+		}
+
+		return names
+	case GroupByDue:
+		return dateGroup(task.DueDate, "No due date")
+	case GroupByHappens:
+		return dateGroup(happensDate(task), "No happens date")
+	case GroupByPriority:
+		return []groupName{priorityGroup(task.Priority)}
+	case GroupByStatus:
+		if task.StatusType.IsDone() {
+			return []groupName{{name: "Done", order: "1"}}
+		}
+
+		return []groupName{{name: "Todo", order: "0"}}
+	default:
+		return []groupName{{name: ""}}
+	}
+}
+
+func dateGroup(date, none string) []groupName {
+	if date == "" {
+		return []groupName{{name: none, order: noValueOrder}}
+	}
+
+	return []groupName{{name: date, order: date}}
+}
+
+// priorityGroup names a priority's group, ordering groups from highest to
+// lowest priority
+func priorityGroup(p Priority) groupName {
+	switch p {
+	case PriorityHighest:
+		return groupName{name: "Highest priority", order: "0"}
+	case PriorityHigh:
+		return groupName{name: "High priority", order: "1"}
+	case PriorityMedium:
+		return groupName{name: "Medium priority", order: "2"}
+	case PriorityLow:
+		return groupName{name: "Low priority", order: "4"}
+	default:
+		return groupName{name: "Normal priority", order: "3"}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func groupSummary(groups []*TaskGroup) map[string][]string {
+	out := map[string][]string{}
+
+	for _, g := range groups {
+		for _, task := range g.Tasks {
+			out[g.Name] = append(out[g.Name], task.Description)
+		}
+	}
+
+	return out
+}
+
+func groupNamesOf(groups []*TaskGroup) []string {
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+
+	return names
+}
+
+func TestGroupTasks(t *testing.T) {
+	tasks := []*Task{
+		{Description: "a", FilePath: "Projects/work.md", Heading: "Todo", Tags: []string{"work", "urgent"},
+			DueDate: "2026-10-20", Priority: PriorityHigh},
+		{Description: "b", FilePath: "inbox.md", Tags: []string{}, StartDate: "2026-10-01", StatusType: StatusTypeDone},
+		{Description: "c", FilePath: "Projects/work.md", Heading: "Todo", Tags: []string{"work"},
+			DueDate: "2026-10-18", Priority: PriorityLow},
+	}
+
+	tests := []struct {
+		key   GroupKey
+		names []string
+		tasks map[string][]string
+	}{
+		{
+			key:   GroupKey{Field: GroupByFolder},
+			names: []string{"/", "Projects/"},
+			tasks: map[string][]string{"/": {"b"}, "Projects/": {"a", "c"}},
+		},
+		{
+			key:   GroupKey{Field: GroupByFilename},
+			names: []string{"inbox", "work"},
+		},
+		{
+			key:   GroupKey{Field: GroupByHeading},
+			names: []string{"Todo", "(No heading)"},
+		},
+		{
+			key:   GroupKey{Field: GroupByTags},
+			names: []string{"#urgent", "#work", "(No tags)"},
+			tasks: map[string][]string{"#urgent": {"a"}, "#work": {"a", "c"}, "(No tags)": {"b"}},
+		},
+		{
+			key:   GroupKey{Field: GroupByDue},
+			names: []string{"2026-10-18", "2026-10-20", "No due date"},
+		},
+		{
+			key:   GroupKey{Field: GroupByDue, Reverse: true},
+			names: []string{"No due date", "2026-10-20", "2026-10-18"},
+		},
+		{
+			key:   GroupKey{Field: GroupByPriority},
+			names: []string{"High priority", "Normal priority", "Low priority"},
+		},
+		{
+			key:   GroupKey{Field: GroupByStatus},
+			names: []string{"Todo", "Done"},
+		},
+		{
+			key:   GroupKey{Field: GroupByHappens},
+			names: []string{"2026-10-01", "2026-10-18", "2026-10-20"},
+		},
+	}
+
+	for _, tt := range tests {
+		groups := groupTasks(tasks, []GroupKey{tt.key}, 0)
+		assert.Equal(t, tt.names, groupNamesOf(groups), "%+v", tt.key)
+
+		if tt.tasks != nil {
+			assert.Equal(t, tt.tasks, groupSummary(groups), "%+v", tt.key)
+		}
+	}
+}
+
+func TestGroupTasksNestedWithLimit(t *testing.T) {
+	tasks := []*Task{
+		{Description: "a", FilePath: "x.md", Tags: []string{"one"}},
+		{Description: "b", FilePath: "x.md", Tags: []string{"one"}},
+		{Description: "c", FilePath: "x.md", Tags: []string{"two"}},
+		{Description: "d", FilePath: "y.md", Tags: []string{"one"}},
+	}
+
+	groups := groupTasks(tasks, []GroupKey{{Field: GroupByFilename}, {Field: GroupByTags}}, 1)
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, "x", groups[0].Name)
+	assert.Equal(t, 3, groups[0].Count)
+	assert.Empty(t, groups[0].Tasks)
+	require.Len(t, groups[0].Groups, 2)
+	assert.Equal(t, "#one", groups[0].Groups[0].Name)
+	assert.Equal(t, 2, groups[0].Groups[0].Count)
+	require.Len(t, groups[0].Groups[0].Tasks, 1)
+	assert.Equal(t, "a", groups[0].Groups[0].Tasks[0].Description)
+
+	assert.Equal(t, "y", groups[1].Name)
+	assert.Equal(t, 1, groups[1].Count)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// listItemRegex matches any markdown list item, task or not, capturing its
+// indentation
+var listItemRegex = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])(?:\s|$)`)
+
+// listEntry is a list item that may still have children
+type listEntry struct {
+	task  *Task // nil for plain list items
+	id    string
+	width int
+}
+
+// listTracker follows the nesting of list items as a file is read line by
+// line, linking each task to its parent and children
+type listTracker struct {
+	stack []listEntry
+}
+
+// add records a line of the file. task is the task parsed from the line, or
+// nil if it is not a task. Tasks get their IndentLevel, parent and ancestors
+// set, and are added to their parent task's ChildIDs.
+func (lt *listTracker) add(line, id string, task *Task) {
+	matches := listItemRegex.FindStringSubmatch(line)
+	if matches == nil {
+		// a paragraph or heading at the left margin ends the list, while
+		// blank and indented lines continue the current item
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			lt.stack = lt.stack[:0]
+		}
+
+		return
+	}
+
+	width := indentWidth(matches[1])
+
+	for len(lt.stack) > 0 && lt.stack[len(lt.stack)-1].width >= width {
+		lt.stack = lt.stack[:len(lt.stack)-1]
+	}
+
+	if task != nil {
+		task.IndentLevel = len(lt.stack)
+
+		for i := len(lt.stack) - 1; i >= 0; i-- {
+			task.ancestorIDs = append(task.ancestorIDs, lt.stack[i].id)
+		}
+
+		if len(lt.stack) > 0 {
+			parent := lt.stack[len(lt.stack)-1]
+
+			task.ParentID = parent.id
+			task.ParentIsTask = parent.task != nil
+
+			if parent.task != nil {
+				parent.task.ChildIDs = append(parent.task.ChildIDs, task.ID)
+			}
+		}
+	}
+
+	lt.stack = append(lt.stack, listEntry{task: task, id: id, width: width})
+}
+
+// indentWidth returns the width of an indent in columns, counting tabs as
+// four columns as Obsidian does
+func indentWidth(indent string) int {
+	width := 0
+
+	for _, r := range indent {
+		if r == '\t' {
+			width += 4 - width%4
+		} else {
+			width++
+		}
+	}
+
+	return width
+}
+
+// TaskNode is a task and the matching tasks nested beneath it
+type TaskNode struct {
+	Task     *Task       `json:"task"`
+	Children []*TaskNode `json:"children,omitempty"`
+}
+
+// taskNodeSchemas describes TaskNode for schema inference, which cannot follow
+// recursive types. Nested children are described as plain objects.
+func taskNodeSchemas() (map[reflect.Type]*jsonschema.Schema, error) {
+	taskSchema, err := jsonschema.For[Task](nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[reflect.Type]*jsonschema.Schema{
+		reflect.TypeFor[TaskNode](): {
+			Type:     "object",
+			Required: []string{"task"},
+			Properties: map[string]*jsonschema.Schema{
+				"task":     taskSchema,
+				"children": {Type: "array", Items: &jsonschema.Schema{Type: "object"}},
+			},
+		},
+	}, nil
+}
+
+// buildTaskTree arranges tasks into trees, placing each task under its
+// nearest ancestor that is also in tasks. Order is preserved among siblings.
+func buildTaskTree(tasks []*Task) []*TaskNode {
+	nodes := make(map[string]*TaskNode, len(tasks))
+	for _, task := range tasks {
+		nodes[task.ID] = &TaskNode{Task: task}
+	}
+
+	roots := make([]*TaskNode, 0, len(tasks))
+
+	for _, task := range tasks {
+		node := nodes[task.ID]
+
+		var parent *TaskNode
+
+		for _, id := range task.ancestorIDs {
+			if p, ok := nodes[id]; ok {
+				parent = p
+
+				break
+			}
+		}
+
+		if parent == nil {
+			roots = append(roots, node)
+		} else {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return roots
+}
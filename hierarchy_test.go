@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseNested parses the tasks in content, tracking list nesting as the
+// scanner does
+func parseNested(content string) []*Task {
+	var (
+		tasks []*Task
+		lists listTracker
+	)
+
+	for i, line := range strings.Split(content, "\n") {
+		task := ParseTask(line, "todo.md", i+1)
+		lists.add(line, taskID("todo.md", i+1), task)
+
+		if task != nil {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks
+}
+
+func TestListTracker(t *testing.T) {
+	tasks := parseNested(`- [ ] Parent
+  - [ ] Child one
+    - [ ] Grandchild
+  - [x] Child two
+- Plain item
+	- [ ] Under plain item
+
+	  continued paragraph
+	- [ ] Sibling after blank line
+Paragraph ends the list
+  - [ ] Indented but top level
+* [ ] Not a task for the parser`)
+
+	require.Len(t, tasks, 7)
+
+	byLine := map[int]*Task{}
+	for _, task := range tasks {
+		byLine[task.LineNumber] = task
+	}
+
+	parent := byLine[1]
+	assert.Equal(t, 0, parent.IndentLevel)
+	assert.Empty(t, parent.ParentID)
+	assert.Equal(t, []string{"todo.md:2", "todo.md:4"}, parent.ChildIDs)
+
+	assert.Equal(t, 1, byLine[2].IndentLevel)
+	assert.Equal(t, "todo.md:1", byLine[2].ParentID)
+	assert.True(t, byLine[2].ParentIsTask)
+	assert.Equal(t, []string{"todo.md:3"}, byLine[2].ChildIDs)
+
+	assert.Equal(t, 2, byLine[3].IndentLevel)
+	assert.Equal(t, []string{"todo.md:2", "todo.md:1"}, byLine[3].ancestorIDs)
+
+	assert.Equal(t, "todo.md:1", byLine[4].ParentID)
+
+	assert.Equal(t, 1, byLine[6].IndentLevel)
+	assert.Equal(t, "todo.md:5", byLine[6].ParentID)
+	assert.False(t, byLine[6].ParentIsTask)
+
+	assert.Equal(t, "todo.md:5", byLine[9].ParentID)
+
+	assert.Equal(t, 0, byLine[11].IndentLevel)
+	assert.Empty(t, byLine[11].ParentID)
+}
+
+func TestIndentWidth(t *testing.T) {
+	assert.Equal(t, 0, indentWidth(""))
+	assert.Equal(t, 2, indentWidth("  "))
+	assert.Equal(t, 4, indentWidth("\t"))
+	assert.Equal(t, 4, indentWidth("  \t"))
+	assert.Equal(t, 6, indentWidth("\t  "))
+}
+
+func TestBuildTaskTree(t *testing.T) {
+	tasks := parseNested(`- [ ] A
+  - Plain
+    - [ ] B
+      - [ ] C
+- [ ] D`)
+
+	roots := buildTaskTree(tasks)
+	require.Len(t, roots, 2)
+	assert.Equal(t, "A", roots[0].Task.Description)
+	require.Len(t, roots[0].Children, 1)
+	assert.Equal(t, "B", roots[0].Children[0].Task.Description)
+	require.Len(t, roots[0].Children[0].Children, 1)
+	assert.Equal(t, "C", roots[0].Children[0].Children[0].Task.Description)
+	assert.Equal(t, "D", roots[1].Task.Description)
+
+	t.Run("filtered out ancestors are skipped", func(t *testing.T) {
+		roots := buildTaskTree([]*Task{tasks[0], tasks[2]})
+		require.Len(t, roots, 1)
+		require.Len(t, roots[0].Children, 1)
+		assert.Equal(t, "C", roots[0].Children[0].Task.Description)
+	})
+}
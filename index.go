@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TaskIndex keeps the tasks of every markdown file under the configured roots
+// in memory, so that queries don't re-read the vault. Files are re-parsed
+// only when their size or modification time changes. Watch keeps the index
+// current, and the write tools refresh the files they change with
+// RefreshFile.
+type TaskIndex struct {
+	// files holds the tasks of each indexed file, by absolute path
+	files map[string]*indexedFile
+	// dirWarnings holds the warnings about folders that couldn't be read, by
+	// absolute path
+	dirWarnings map[string]ScanWarning
+	roots       []*indexRoot
+	exclude     []string
+	mu          sync.RWMutex
+	// workers is the number of files parsed concurrently
+	workers int
+	// ready is set once every root has been indexed successfully. Until
+	// then, scans read the files instead.
+	ready bool
+}
+
+// indexRoot is a configured root directory and the vault settings its files
+// are parsed with
+type indexRoot struct {
+	settings *VaultSettings
+	rules    *excludeRules
+	// dir is the absolute path of the root, and resolved the same with
+	// symlinks resolved
+	dir      string
+	resolved string
+	// configStamp identifies the versions of the vault's settings files, to
+	// notice when they change
+	configStamp string
+}
+
+type indexedFile struct {
+	modTime  time.Time
+	tasks    []*Task
+	warnings []ScanWarning
+	size     int64
+	// unreadable is set when the file couldn't be read. Such files are read
+	// again on every refresh, since fixing their permissions doesn't change
+	// their size or modification time.
+	unreadable bool
+}
+
+// NewTaskIndex returns an empty index of the markdown files under roots,
+// skipping files and folders matching the exclude globs and parsing up to
+// workers files at a time (one per CPU if workers is not positive). Call
+// Refresh or Watch to populate it.
+func NewTaskIndex(roots, exclude []string, workers int) (*TaskIndex, error) {
+	if err := validateGlobs(exclude); err != nil {
+		return nil, err
+	}
+
+	ix := &TaskIndex{
+		files:       make(map[string]*indexedFile),
+		dirWarnings: make(map[string]ScanWarning),
+		exclude:     exclude,
+		workers:     workers,
+	}
+
+	for _, root := range roots {
+		dir, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for %q: %w", root, err)
+		}
+
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			resolved = dir
+		}
+
+		ix.roots = append(ix.roots, &indexRoot{dir: dir, resolved: resolved})
+	}
+
+	return ix, nil
+}
+
+// Refresh brings the whole index up to date: new and changed files are
+// parsed, and deleted files are dropped. When a vault's settings have
+// changed, all of its files are parsed again. A refresh interrupted by ctx
+// leaves the index as it was.
+func (ix *TaskIndex) Refresh(ctx context.Context) error {
+	var errs []error
+
+	for _, root := range ix.roots {
+		if err := ix.refreshRoot(ctx, root); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err := errors.Join(errs...)
+
+	ix.mu.Lock()
+	ix.ready = err == nil
+	ix.mu.Unlock()
+
+	return err
+}
+
+func (ix *TaskIndex) refreshRoot(ctx context.Context, root *indexRoot) error {
+	stamp := configStamp(root.dir)
+
+	ix.mu.RLock()
+	changed := root.rules == nil || stamp != root.configStamp
+	ix.mu.RUnlock()
+
+	if changed {
+		settings, err := LoadVaultSettings(root.dir)
+		if err != nil {
+			return err
+		}
+
+		rules, err := newExcludeRules(root.dir, ix.exclude)
+		if err != nil {
+			return err
+		}
+
+		ix.mu.Lock()
+		root.settings, root.rules, root.configStamp = settings, rules, stamp
+		ix.mu.Unlock()
+	}
+
+	return ix.refreshTree(ctx, root, root.dir, changed)
+}
+
+// refreshTree updates the index for the files in dir, inside root. With
+// reparse set, unchanged files are parsed again too.
+func (ix *TaskIndex) refreshTree(ctx context.Context, root *indexRoot, dir string, reparse bool) error {
+	ix.mu.RLock()
+	settings, rules := root.settings, root.rules
+	ix.mu.RUnlock()
+
+	var (
+		found   = make(map[string]*indexedFile)
+		foundMu sync.Mutex
+	)
+
+	dirWarnings, err := walkMarkdownFiles(ctx, dir, rules, ix.workers, func(path string, info os.FileInfo) {
+		ix.mu.RLock()
+		file := ix.files[path]
+		ix.mu.RUnlock()
+
+		if reparse || !file.matches(info) {
+			file = parseIndexedFile(path, root.dir, settings, info)
+		}
+
+		foundMu.Lock()
+		found[path] = file
+		foundMu.Unlock()
+	})
+	if ctx.Err() != nil {
+		// found is incomplete, so don't drop the files it's missing
+		return ctx.Err()
+	}
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to walk directory %q: %w", dir, err)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for path := range ix.files {
+		if _, ok := found[path]; !ok && isWithin(dir, path) {
+			delete(ix.files, path)
+		}
+	}
+
+	maps.Copy(ix.files, found)
+
+	maps.DeleteFunc(ix.dirWarnings, func(path string, _ ScanWarning) bool {
+		return isWithin(dir, path)
+	})
+
+	for _, warning := range dirWarnings {
+		ix.dirWarnings[filepath.Join(root.dir, warning.File)] = warning
+	}
+
+	return nil
+}
+
+// RefreshFile updates the index for a single file or folder that may have
+// been created, changed or deleted. It does nothing on a nil index.
+func (ix *TaskIndex) RefreshFile(path string) {
+	if ix == nil {
+		return
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	root := ix.rootOf(path)
+	if root == nil {
+		return
+	}
+
+	ix.mu.RLock()
+	settings, rules := root.settings, root.rules
+	ix.mu.RUnlock()
+
+	if rules == nil {
+		// the root has not been indexed yet
+		return
+	}
+
+	info, err := os.Stat(path)
+
+	switch {
+	case err != nil || rules.excludes(path) || (info.IsDir() && path != root.dir && isSymlink(path)):
+		// scans don't follow symlinked folders inside a root either
+		ix.remove(path)
+	case info.IsDir():
+		if err := ix.refreshTree(context.Background(), root, path, false); err != nil {
+			log.Printf("failed to index %q: %v", path, err)
+		}
+	case strings.HasSuffix(strings.ToLower(path), ".md"):
+		ix.mu.RLock()
+		unchanged := ix.files[path].matches(info)
+		ix.mu.RUnlock()
+
+		if unchanged {
+			return
+		}
+
+		file := parseIndexedFile(path, root.dir, settings, info)
+
+		ix.mu.Lock()
+		ix.files[path] = file
+		ix.mu.Unlock()
+	}
+}
+
+// isSymlink reports whether path is a symbolic link
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// remove drops a file, or every file in a folder, from the index
+func (ix *TaskIndex) remove(path string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for file := range ix.files {
+		if isWithin(path, file) {
+			delete(ix.files, file)
+		}
+	}
+
+	maps.DeleteFunc(ix.dirWarnings, func(dir string, _ ScanWarning) bool {
+		return isWithin(path, dir)
+	})
+}
+
+// rootOf returns the first root containing path
+func (ix *TaskIndex) rootOf(path string) *indexRoot {
+	for _, root := range ix.roots {
+		if isWithin(root.dir, path) {
+			return root
+		}
+	}
+
+	return nil
+}
+
+// collect returns copies of the indexed tasks and warnings under roots, as
+// collectTasks would find them. It returns false if the index is nil, not
+// ready or doesn't cover every root, in which case the files must be read
+// instead.
+func (ix *TaskIndex) collect(roots, exclude []string) ([]*Task, []ScanWarning, bool, error) {
+	if ix == nil {
+		return nil, nil, false, nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if !ix.ready {
+		return nil, nil, false, nil
+	}
+
+	var (
+		allTasks = make([]*Task, 0)
+		warnings []ScanWarning
+	)
+
+	for _, root := range roots {
+		dir, ok := ix.indexedDir(root)
+		if !ok {
+			return nil, nil, false, nil
+		}
+
+		rules, err := newExcludeRules(dir, exclude)
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		for path, warning := range ix.dirWarnings {
+			if !isWithin(dir, path) || rules.excludes(path) {
+				continue
+			}
+
+			if relPath, err := filepath.Rel(dir, path); err == nil {
+				warning.File = relPath
+				warnings = append(warnings, warning)
+			}
+		}
+
+		for path, file := range ix.files {
+			if !isWithin(dir, path) || rules.excludes(path) {
+				continue
+			}
+
+			relPath, err := filepath.Rel(dir, path)
+			if err != nil {
+				continue
+			}
+
+			for _, task := range file.tasks {
+				// queries set fields such as urgency and blocked on the
+				// tasks they return, so each gets its own copy
+				c := *task
+				c.FilePath = relPath
+				allTasks = append(allTasks, &c)
+			}
+
+			for _, warning := range file.warnings {
+				warning.File = relPath
+				warnings = append(warnings, warning)
+			}
+		}
+	}
+
+	return allTasks, warnings, true, nil
+}
+
+// indexedDir maps a directory to scan to its absolute path under one of the
+// indexed roots, which may be spelled differently once symlinks are resolved
+func (ix *TaskIndex) indexedDir(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for _, root := range ix.roots {
+		if isWithin(root.dir, dir) {
+			return dir, true
+		}
+
+		if isWithin(root.resolved, dir) {
+			rel, err := filepath.Rel(root.resolved, dir)
+			if err == nil {
+				return filepath.Join(root.dir, rel), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Watch keeps the index current until ctx is done, populating it first. It
+// watches for file changes where the platform allows, and otherwise (or if
+// watching fails) refreshes the whole index every pollInterval.
+func (ix *TaskIndex) Watch(ctx context.Context, pollInterval time.Duration) {
+	err := ix.watchEvents(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	log.Printf("watching for file changes failed, polling every %s instead: %v", pollInterval, err)
+
+	ix.poll(ctx, pollInterval)
+}
+
+// poll refreshes the index every interval until ctx is done
+func (ix *TaskIndex) poll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		logIndexError(ix.Refresh(ctx))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func logIndexError(err error) {
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("failed to index tasks: %v", err)
+	}
+}
+
+// matches reports whether the indexed file is still current for a file with
+// the given info. A nil *indexedFile matches nothing.
+func (f *indexedFile) matches(info os.FileInfo) bool {
+	return f != nil && !f.unreadable && f.size == info.Size() && f.modTime.Equal(info.ModTime())
+}
+
+// parseIndexedFile parses the file at path, recording a warning instead of
+// its tasks if it can't be read
+func parseIndexedFile(path, rootDir string, settings *VaultSettings, info os.FileInfo) *indexedFile {
+	file := &indexedFile{size: info.Size(), modTime: info.ModTime()}
+
+	tasks, warnings, err := parseTasksFromFile(path, rootDir, settings)
+	if err != nil {
+		file.unreadable = true
+		file.warnings = []ScanWarning{newScanWarning(rootDir, path, WarningUnreadableFile, err)}
+
+		return file
+	}
+
+	file.tasks, file.warnings = tasks, warnings
+
+	return file
+}
+
+// configStamp identifies the versions of the settings files of the vault
+// containing dir
+func configStamp(dir string) string {
+	configDir, ok := findConfigDir(dir)
+	if !ok {
+		return ""
+	}
+
+	var stamp strings.Builder
+
+	for _, name := range []string{"app.json", tasksPluginSettingsPath} {
+		if info, err := os.Stat(filepath.Join(configDir, name)); err == nil {
+			fmt.Fprintf(&stamp, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+
+	return configDir + ";" + stamp.String()
+}
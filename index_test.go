@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// indexedDescriptions returns the descriptions of the tasks the index has
+// for roots, sorted, or nil if the index can't answer
+func indexedDescriptions(t *testing.T, ix *TaskIndex, roots []string, exclude ...string) []string {
+	t.Helper()
+
+	tasks, _, ok, err := ix.collect(roots, exclude)
+	require.NoError(t, err)
+
+	if !ok {
+		return nil
+	}
+
+	descriptions := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		descriptions = append(descriptions, task.FilePath+": "+task.Description)
+	}
+
+	slices.Sort(descriptions)
+
+	return descriptions
+}
+
+func writeNote(t *testing.T, vault, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(vault, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestTaskIndex(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] One\n- [x] Two\n")
+	writeNote(t, vault, "Projects/work.md", "- [ ] Three\n")
+	writeNote(t, vault, "Archive/old.md", "- [ ] Old\n")
+	writeNote(t, vault, ".trash/gone.md", "- [ ] Gone\n")
+
+	ix, err := NewTaskIndex([]string{vault}, []string{"Archive"}, 0)
+	require.NoError(t, err)
+
+	// not ready until the first refresh
+	assert.Nil(t, indexedDescriptions(t, ix, []string{vault}))
+
+	require.NoError(t, ix.Refresh(t.Context()))
+	assert.Equal(t, []string{
+		"Projects/work.md: Three",
+		"todo.md: One",
+		"todo.md: Two",
+	}, indexedDescriptions(t, ix, []string{vault}))
+
+	// folders inside a root, and more excludes
+	assert.Equal(t, []string{"work.md: Three"}, indexedDescriptions(t, ix, []string{filepath.Join(vault, "Projects")}))
+	assert.Equal(t, []string{"Projects/work.md: Three"}, indexedDescriptions(t, ix, []string{vault}, "todo.md"))
+
+	// directories outside the index can't be answered from it
+	assert.Nil(t, indexedDescriptions(t, ix, []string{t.TempDir()}))
+
+	t.Run("refresh file", func(t *testing.T) {
+		writeNote(t, vault, "todo.md", "- [ ] One, edited\n")
+		ix.RefreshFile(filepath.Join(vault, "todo.md"))
+
+		writeNote(t, vault, "New/deep/note.md", "- [ ] Four\n")
+		ix.RefreshFile(filepath.Join(vault, "New"))
+
+		require.NoError(t, os.RemoveAll(filepath.Join(vault, "Projects")))
+		ix.RefreshFile(filepath.Join(vault, "Projects"))
+
+		assert.Equal(t, []string{
+			"New/deep/note.md: Four",
+			"todo.md: One, edited",
+		}, indexedDescriptions(t, ix, []string{vault}))
+	})
+
+	t.Run("canceled refresh", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		require.NoError(t, os.Remove(filepath.Join(vault, "todo.md")))
+		require.ErrorIs(t, ix.Refresh(ctx), context.Canceled)
+
+		// the index isn't trusted until a refresh completes, and still has
+		// the files the interrupted refresh didn't get to
+		assert.Nil(t, indexedDescriptions(t, ix, []string{vault}))
+		assert.Contains(t, ix.files, filepath.Join(vault, "todo.md"))
+
+		require.NoError(t, ix.Refresh(t.Context()))
+		assert.Equal(t, []string{"New/deep/note.md: Four"}, indexedDescriptions(t, ix, []string{vault}))
+	})
+
+	t.Run("refresh reloads settings", func(t *testing.T) {
+		writeNote(t, vault, "tagged.md", "- [ ] #task Five\n")
+		writeTasksSettings(t, vault, `{"globalFilter": "#task"}`)
+
+		require.NoError(t, ix.Refresh(t.Context()))
+		assert.Equal(t, []string{"tagged.md: Five"}, indexedDescriptions(t, ix, []string{vault}))
+	})
+}
+
+func TestScanVaultWithIndex(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] Blocked ⛔ a\n- [ ] Blocker 🆔 a 📅 2026-10-16\n")
+
+	ix, err := NewTaskIndex([]string{vault}, nil, 0)
+	require.NoError(t, err)
+	require.NoError(t, ix.Refresh(t.Context()))
+
+	// answered from the index even though the file is gone
+	require.NoError(t, os.Remove(filepath.Join(vault, "todo.md")))
+
+	query, err := ParseQuery("is blocked")
+	require.NoError(t, err)
+
+	for range 2 {
+		result, err := ScanVaultWithOptions(t.Context(), []string{vault}, query, ScanOptions{Index: ix})
+		require.NoError(t, err)
+		require.Len(t, result.Tasks, 1)
+		assert.Equal(t, "Blocked", result.Tasks[0].Description)
+		assert.Equal(t, "todo.md", result.Tasks[0].FilePath)
+	}
+
+	// the indexed tasks themselves are never changed by queries
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	for _, file := range ix.files {
+		for _, task := range file.tasks {
+			assert.False(t, task.Blocked)
+			assert.False(t, task.Blocking)
+			assert.Zero(t, task.Urgency)
+		}
+	}
+}
+
+func TestTaskIndexWriteTools(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] Buy milk\n")
+
+	ix, err := NewTaskIndex([]string{vault}, nil, 0)
+	require.NoError(t, err)
+	require.NoError(t, ix.Refresh(t.Context()))
+
+	s := testServer(t, vault)
+	s.index = ix
+
+	res, _, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "todo.md:1", Description: "Buy milk"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	res, _, err = s.createTask(t.Context(), nil, CreateTaskInput{Description: "Bake bread", Path: "todo.md"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	// no watcher is running, so the index is current only because the write
+	// tools refreshed it
+	res, out, err := s.queryTasks(t.Context(), nil, QueryTasksInput{Query: "done"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.Len(t, out.Tasks, 1)
+	assert.Equal(t, "Buy milk", out.Tasks[0].Description)
+
+	assert.Equal(t, []string{"todo.md: Bake bread", "todo.md: Buy milk"}, indexedDescriptions(t, ix, []string{vault}))
+}
+
+func TestTaskIndexSymlinkedRoot(t *testing.T) {
+	base := t.TempDir()
+	vault := filepath.Join(base, "vault")
+	link := filepath.Join(base, "link")
+
+	writeNote(t, vault, "todo.md", "- [ ] One\n")
+	writeNote(t, vault, "Projects/work.md", "- [ ] Two\n")
+	writeNote(t, base, "elsewhere/other.md", "- [ ] Elsewhere\n")
+	require.NoError(t, os.Symlink(vault, link))
+
+	ix, err := NewTaskIndex([]string{link}, nil, 0)
+	require.NoError(t, err)
+	require.NoError(t, ix.Refresh(t.Context()))
+
+	want := []string{"Projects/work.md: Two", "todo.md: One"}
+	assert.Equal(t, want, indexedDescriptions(t, ix, []string{link}))
+	assert.Equal(t, want, indexedDescriptions(t, ix, []string{vault}))
+
+	// ids use the root as configured
+	tasks, _, _, err := ix.collect([]string{link}, nil)
+	require.NoError(t, err)
+
+	for _, task := range tasks {
+		assert.True(t, strings.HasPrefix(task.ID, link+string(filepath.Separator)), task.ID)
+	}
+
+	writeNote(t, vault, "New/note.md", "- [ ] Three\n")
+	ix.RefreshFile(filepath.Join(link, "New"))
+	assert.Equal(t, []string{"New/note.md: Three", "Projects/work.md: Two", "todo.md: One"},
+		indexedDescriptions(t, ix, []string{link}))
+
+	// symlinked folders inside the root are skipped, as a scan would
+	require.NoError(t, os.Symlink(filepath.Join(base, "elsewhere"), filepath.Join(vault, "elsewhere")))
+	ix.RefreshFile(filepath.Join(link, "elsewhere"))
+	require.NoError(t, ix.Refresh(t.Context()))
+	assert.Equal(t, []string{"New/note.md: Three", "Projects/work.md: Two", "todo.md: One"},
+		indexedDescriptions(t, ix, []string{link}))
+}
+
+func TestTaskIndexPoll(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] One\n")
+
+	ix, err := NewTaskIndex([]string{vault}, nil, 0)
+	require.NoError(t, err)
+
+	go ix.poll(t.Context(), 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"todo.md: One"}, indexedDescriptions(t, ix, []string{vault}))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeNote(t, vault, "later.md", "- [ ] Two\n")
+
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"later.md: Two", "todo.md: One"}, indexedDescriptions(t, ix, []string{vault}))
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTaskIndexWarnings(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] One\n"+strings.Repeat("x", maxLineLength)+"\n")
+
+	target := filepath.Join(vault, "target.txt")
+	require.NoError(t, os.Symlink(target, filepath.Join(vault, "broken.md")))
+
+	ix, err := NewTaskIndex([]string{vault}, nil, 0)
+	require.NoError(t, err)
+	require.NoError(t, ix.Refresh(t.Context()))
+
+	_, warnings, ok, err := ix.collect([]string{vault}, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	sortWarnings(warnings)
+	assert.Equal(t, []ScanWarning{
+		{File: "broken.md", Kind: WarningUnreadableFile, Message: "no such file or directory"},
+		{File: "todo.md", Kind: WarningLineTooLong, Message: "line 2 is longer than 65536 bytes and was skipped"},
+	}, warnings)
+
+	// the symlink itself is unchanged once its target exists, but unreadable
+	// files are always read again
+	require.NoError(t, os.WriteFile(target, []byte("- [ ] Two\n"), 0o600))
+	require.NoError(t, ix.Refresh(t.Context()))
+
+	assert.Equal(t, []string{"broken.md: Two", "todo.md: One"}, indexedDescriptions(t, ix, []string{vault}))
+
+	_, warnings, _, err = ix.collect([]string{vault}, []string{"todo.md"})
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
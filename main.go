@@ -2,104 +2,236 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"time"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 type QueryTasksInput struct {
 	Query string `json:"query" jsonschema:"Tasks query string with filters (one filter per line). Example: not done\ntag include #shopping"`
 
-	RootDirs []string `json:"rootDirs" jsonschema:"Root directories to scan for markdown files"`
+	RootDirs []string `json:"rootDirs,omitempty" jsonschema:"Directories to scan for markdown files. Each must be inside a configured -root directory. Defaults to all -root directories"`
+
+	Lenient bool `json:"lenient,omitempty" jsonschema:"Skip unknown query lines instead of reporting them as errors"`
+
+	Tree bool `json:"tree,omitempty" jsonschema:"Also return the matching tasks as a tree, with subtasks nested under their parent tasks"`
+
+	Exclude []string `json:"exclude,omitempty" jsonschema:"Glob patterns of files and folders to skip, relative to the scanned directory, e.g. Archive or *.excalidraw.md. Added to the -exclude patterns"`
 }
 
 type QueryTasksOutput struct {
-	Tasks []*Task `json:"tasks"`
-	Total int     `json:"total"`
+	Tasks []*Task     `json:"tasks"`
+	Tree  []*TaskNode `json:"tree,omitempty"`
+	// Groups holds the tasks grouped by the query's "group by" instructions
+	Groups      []*TaskGroup     `json:"groups,omitempty"`
+	QueryErrors []QueryLineError `json:"queryErrors,omitempty"`
+	// DependencyCycles lists groups of tasks whose ⛔ dependencies form a
+	// cycle, by task id
+	DependencyCycles [][]string `json:"dependencyCycles,omitempty"`
+	// Warnings lists the files and folders that couldn't be fully read, so
+	// that their tasks may be missing
+	Warnings []ScanWarning `json:"warnings,omitempty"`
+	Total    int           `json:"total"`
 }
 
-func queryTasks(_ context.Context, _ *mcp.CallToolRequest, input QueryTasksInput) (
+// taskServer holds the configuration shared by the MCP tool handlers
+type taskServer struct {
+	queryOptions QueryOptions
+	// roots are the directories configured with -root. Tools that modify
+	// files only ever touch files inside these directories.
+	roots []string
+	// exclude lists the globs of files and folders configured with -exclude,
+	// which scans skip
+	exclude []string
+	// index caches the tasks under roots. Without it, every scan reads the
+	// files.
+	index *TaskIndex
+	// workers is the number of files scans parse concurrently
+	workers int
+}
+
+// errorResult returns a tool result reporting the given error message
+func errorResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: text,
+			},
+		},
+	}
+}
+
+func (s *taskServer) queryTasks(ctx context.Context, _ *mcp.CallToolRequest, input QueryTasksInput) (
 	*mcp.CallToolResult,
 	QueryTasksOutput,
 	error,
 ) {
-	// Use rootDirs from input, or fall back to command-line args
-	roots := input.RootDirs
-	if len(roots) == 0 {
-		// This shouldn't happen if rootDirs is required, but handle it gracefully
-		return &mcp.CallToolResult{
-			IsError: true,
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Text: "rootDirs parameter is required",
-				},
-			},
-		}, QueryTasksOutput{Tasks: []*Task{}}, nil
+	roots, err := s.scanRoots(input.RootDirs)
+	if err != nil {
+		return errorResult(err.Error()), QueryTasksOutput{Tasks: []*Task{}}, nil
 	}
 
-	// Parse query
-	var query *Query
+	// Parse query. An empty query is parsed too, so that urgency is computed
+	// from today in the configured timezone.
+	opts := s.queryOptions
+	opts.Lenient = input.Lenient
 
-	var err error
+	query, err := ParseQueryWithOptions(input.Query, opts)
 
-	if input.Query != "" {
-		query, err = ParseQuery(input.Query)
-		if err != nil {
-			return &mcp.CallToolResult{
-				IsError: true,
-				Content: []mcp.Content{
-					&mcp.TextContent{
-						Text: "failed to parse query: " + err.Error(),
-					},
-				},
-			}, QueryTasksOutput{Tasks: []*Task{}}, err
-		}
+	var qerr *QueryError
+	if errors.As(err, &qerr) {
+		// report each bad line in the structured output so clients can
+		// point at the offending text
+		return errorResult("invalid query: " + qerr.Error()), QueryTasksOutput{Tasks: []*Task{}, QueryErrors: qerr.Errors}, nil
+	}
+
+	if err != nil {
+		return errorResult("failed to parse query: " + err.Error()), QueryTasksOutput{Tasks: []*Task{}}, err
+	}
+
+	exclude := slices.Concat(s.exclude, input.Exclude)
+	if err := validateGlobs(exclude); err != nil {
+		return errorResult(err.Error()), QueryTasksOutput{Tasks: []*Task{}}, nil
 	}
 
 	// Scan tasks
-	tasks, total, err := ScanTasksWithQuery(roots, query)
+	result, err := ScanVaultWithOptions(ctx, roots, query, ScanOptions{Exclude: exclude, Index: s.index, Workers: s.workers})
 	if err != nil {
-		return &mcp.CallToolResult{
-			IsError: true,
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Text: "failed to scan tasks: " + err.Error(),
-				},
-			},
-		}, QueryTasksOutput{Tasks: []*Task{}}, err
+		return errorResult("failed to scan tasks: " + err.Error()), QueryTasksOutput{Tasks: []*Task{}}, err
 	}
 
-	return nil, QueryTasksOutput{Tasks: tasks, Total: total}, nil
+	out := QueryTasksOutput{Tasks: result.Tasks, Total: result.Total, DependencyCycles: result.Cycles, Warnings: result.Warnings}
+	if input.Tree {
+		out.Tree = buildTaskTree(result.Tasks)
+	}
+
+	if len(query.GroupBy) > 0 {
+		out.Groups = groupTasks(result.Tasks, query.GroupBy, query.GroupLimit)
+	}
+
+	return nil, out, nil
+}
+
+// scanRoots returns the directories to scan for a query, with symlinks
+// resolved: the requested directories, which must each be inside a
+// configured root once symlinks and ".." are resolved, or all configured roots
+// if none are requested
+func (s *taskServer) scanRoots(requested []string) ([]string, error) {
+	if len(s.roots) == 0 {
+		return nil, errors.New("no root directories are configured")
+	}
+
+	allowed := make([]string, 0, len(s.roots))
+
+	for _, root := range s.roots {
+		resolved, err := realPath(root)
+		if err != nil {
+			if len(requested) == 0 {
+				return nil, fmt.Errorf("invalid root directory %q: %w", root, err)
+			}
+
+			continue
+		}
+
+		allowed = append(allowed, resolved)
+	}
+
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	roots := make([]string, 0, len(requested))
+
+	for _, dir := range requested {
+		resolved, err := realPath(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root directory %q: %w", dir, err)
+		}
+
+		if !slices.ContainsFunc(allowed, func(root string) bool { return isWithin(root, resolved) }) {
+			return nil, fmt.Errorf("root directory %q is not inside a configured -root directory", dir)
+		}
+
+		roots = append(roots, resolved)
+	}
+
+	return roots, nil
 }
 
 func main() {
 	var rootDirs flagList
 	flag.Var(&rootDirs, "root", "Root directory to scan for markdown files (can be specified multiple times)")
+	var exclude flagList
+	flag.Var(&exclude, "exclude",
+		"Glob pattern of files and folders to skip when scanning, e.g. Archive or *.excalidraw.md (can be specified multiple times)")
+	workers := flag.Int("workers", 0, "Number of files to parse concurrently (default: one per CPU)")
+	pollInterval := flag.Duration("poll-interval", 5*time.Second,
+		"How often to rescan the vault for changes when file watching is unavailable")
+	timezone := flag.String("timezone", "", "IANA timezone used to resolve relative dates such as \"today\" (default: local time)")
 	flag.Parse()
 
 	if len(rootDirs) == 0 {
 		log.Fatal("at least one -root directory must be specified")
 	}
 
-	// Create MCP server
-	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "obsidian-tasks",
-		Version: "0.1.0",
-	}, nil)
+	loc, err := loadLocation(*timezone)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Add the query_tasks tool
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "query_tasks",
-		Description: "Query Obsidian tasks from markdown files using Tasks query filters",
-	}, queryTasks)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	index, err := startIndex(ctx, rootDirs, exclude, *workers, *pollInterval)
+	if err != nil {
+		log.Fatal(err) //nolint:gocritic // exiting without the deferred cancel is fine
+	}
+
+	server := newServer(&taskServer{
+		queryOptions: QueryOptions{Now: time.Now, Location: loc},
+		roots:        rootDirs,
+		exclude:      exclude,
+		index:        index,
+		workers:      *workers,
+	})
 
 	// Run the server over stdin/stdout
-	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		log.Fatal(err)
+	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil {
+		log.Fatal(err) //nolint:gocritic // exiting without the deferred cancel is fine
 	}
 }
 
+// queryTasksOutputSchema returns the output schema of query_tasks, which
+// can't be inferred automatically because task trees and groups are recursive
+func queryTasksOutputSchema() *jsonschema.Schema {
+	typeSchemas, err := taskNodeSchemas()
+	if err != nil {
+		log.Fatalf("failed to build task tree schema: %v", err)
+	}
+
+	groupSchemas, err := taskGroupSchemas()
+	if err != nil {
+		log.Fatalf("failed to build task group schema: %v", err)
+	}
+
+	maps.Copy(typeSchemas, groupSchemas)
+
+	schema, err := jsonschema.For[QueryTasksOutput](&jsonschema.ForOptions{TypeSchemas: typeSchemas})
+	if err != nil {
+		log.Fatalf("failed to build query_tasks output schema: %v", err)
+	}
+
+	return schema
+}
+
 // flagList is a custom flag type that allows multiple values
 type flagList []string
 
@@ -112,3 +244,73 @@ func (f *flagList) Set(value string) error {
 
 	return nil
 }
+
+// loadLocation returns the -timezone location, or local time if none is set
+func loadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -timezone %q: %w", timezone, err)
+	}
+
+	return loc, nil
+}
+
+// startIndex creates the task index and keeps it current until ctx is done.
+// The vault is indexed in the background; until it is ready, queries read the
+// files.
+func startIndex(ctx context.Context, roots, exclude []string, workers int, pollInterval time.Duration) (*TaskIndex, error) {
+	index, err := NewTaskIndex(roots, exclude, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	go index.Watch(ctx, pollInterval)
+
+	return index, nil
+}
+
+// newServer creates the MCP server, with ts's tools
+func newServer(ts *taskServer) *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "obsidian-tasks",
+		Version: "0.1.0",
+	}, nil)
+
+	// Add the query_tasks tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:         "query_tasks",
+		Description:  "Query Obsidian tasks from markdown files using Tasks query filters",
+		OutputSchema: queryTasksOutputSchema(),
+	}, ts.queryTasks)
+
+	// Add the toggle_task tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "toggle_task",
+		Description: "Toggle a task's status (e.g. mark it done) in its markdown file, identified by the id returned by query_tasks",
+	}, ts.toggleTask)
+
+	// Add the create_task tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "create_task",
+		Description: "Create a task in a markdown file, under a heading, or in today's daily note",
+	}, ts.createTask)
+
+	// Add the update_task tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "update_task",
+		Description: "Change fields (description, dates, priority, tags, status) of an existing task, leaving the rest of its line untouched",
+	}, ts.updateTask)
+
+	// Add the next_actions tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "next_actions",
+		Description: "List the tasks to work on next: those not done, not blocked by dependencies and startable today, " +
+			"ranked by due and scheduled dates, priority and status, with the reasons for each ranking",
+	}, ts.nextActions)
+
+	return server
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanRoots(t *testing.T) {
+	base := t.TempDir()
+	vault := filepath.Join(base, "vault")
+	outside := filepath.Join(base, "outside")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(vault, "Projects"), 0o755))
+	require.NoError(t, os.MkdirAll(outside, 0o755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(vault, "escape")))
+
+	s := testServer(t, vault)
+
+	resolvedVault, err := realPath(vault)
+	require.NoError(t, err)
+
+	t.Run("defaults to the configured roots", func(t *testing.T) {
+		roots, err := s.scanRoots(nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{resolvedVault}, roots)
+	})
+
+	t.Run("resolves symlinked roots either way", func(t *testing.T) {
+		link := filepath.Join(base, "link")
+		require.NoError(t, os.Symlink(vault, link))
+
+		s := testServer(t, link)
+
+		roots, err := s.scanRoots(nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{resolvedVault}, roots)
+
+		roots, err = s.scanRoots([]string{filepath.Join(link, "Projects")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(resolvedVault, "Projects")}, roots)
+	})
+
+	t.Run("allows the root and directories inside it", func(t *testing.T) {
+		roots, err := s.scanRoots([]string{vault, filepath.Join(vault, "Projects")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{resolvedVault, filepath.Join(resolvedVault, "Projects")}, roots)
+	})
+
+	for name, dir := range map[string]string{
+		"outside":        outside,
+		"dot-dot escape": filepath.Join(vault, "Projects", "..", "..", "outside"),
+		"symlink escape": filepath.Join(vault, "escape"),
+		"missing":        filepath.Join(vault, "missing"),
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := s.scanRoots([]string{dir})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("no configured roots", func(t *testing.T) {
+		_, err := testServer(t).scanRoots(nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestQueryTasksRoots(t *testing.T) {
+	base := t.TempDir()
+	vault := filepath.Join(base, "vault")
+	outside := filepath.Join(base, "outside")
+
+	require.NoError(t, os.MkdirAll(vault, 0o755))
+	require.NoError(t, os.MkdirAll(outside, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "todo.md"), []byte("- [ ] In the vault\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.md"), []byte("- [ ] Secret\n"), 0o600))
+
+	s := testServer(t, vault)
+
+	res, out, err := s.queryTasks(t.Context(), nil, QueryTasksInput{})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.Len(t, out.Tasks, 1)
+	assert.Equal(t, "In the vault", out.Tasks[0].Description)
+
+	res, out, err = s.queryTasks(t.Context(), nil, QueryTasksInput{RootDirs: []string{outside}})
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.True(t, res.IsError)
+	assert.Empty(t, out.Tasks)
+}
+
+func TestQueryTasksTree(t *testing.T) {
+	vault := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "todo.md"),
+		[]byte("- [ ] Parent\n  - [ ] Child\n- [ ] Other\n"), 0o600))
+
+	s := testServer(t, vault)
+
+	res, out, err := s.queryTasks(t.Context(), nil, QueryTasksInput{Tree: true})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.Len(t, out.Tasks, 3)
+	require.Len(t, out.Tree, 2)
+	assert.Equal(t, "Parent", out.Tree[0].Task.Description)
+	require.Len(t, out.Tree[0].Children, 1)
+	assert.Equal(t, "Child", out.Tree[0].Children[0].Task.Description)
+
+	res, out, err = s.queryTasks(t.Context(), nil, QueryTasksInput{Query: "exclude sub-items"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	assert.Len(t, out.Tasks, 2)
+	assert.Nil(t, out.Tree)
+}
+
+func TestQueryTasksWarnings(t *testing.T) {
+	vault := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "todo.md"), []byte("- [ ] Task\n"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(vault, "missing"), filepath.Join(vault, "broken.md")))
+
+	res, out, err := testServer(t, vault).queryTasks(t.Context(), nil, QueryTasksInput{})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.Len(t, out.Tasks, 1)
+	assert.Equal(t, []ScanWarning{
+		{File: "broken.md", Kind: WarningUnreadableFile, Message: "no such file or directory"},
+	}, out.Warnings)
+}
+
+func TestQueryTasksOutputSchema(t *testing.T) {
+	schema := queryTasksOutputSchema()
+	require.NotNil(t, schema)
+	assert.Contains(t, schema.Properties, "tree")
+	assert.Contains(t, schema.Properties, "groups")
+
+	// the SDK validates structured output against the schema, so make sure
+	// nested trees, groups and warnings pass
+	vault := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "todo.md"),
+		[]byte("- [ ] Parent #a\n  - [ ] Child #b\n    - [ ] Grandchild\n"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(vault, "missing"), filepath.Join(vault, "broken.md")))
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "query_tasks", OutputSchema: schema}, testServer(t, vault).queryTasks)
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+
+	ss, err := server.Connect(t.Context(), serverTransport, nil)
+	require.NoError(t, err)
+
+	defer ss.Close()
+
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "0"}, nil).Connect(t.Context(), clientTransport, nil)
+	require.NoError(t, err)
+
+	defer cs.Close()
+
+	res, err := cs.CallTool(t.Context(), &mcp.CallToolParams{
+		Name:      "query_tasks",
+		Arguments: map[string]any{"query": "group by filename\ngroup by tags", "tree": true},
+	})
+	require.NoError(t, err)
+	assert.False(t, res.IsError, "%v", res.Content)
+}
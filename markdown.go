@@ -0,0 +1,95 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var fenceRegex = regexp.MustCompile("^(`{3,}|~{3,})")
+
+// markdownLines tracks the block context of successive lines of a note, so
+// that task-like lines in YAML frontmatter, fenced code blocks and comments
+// (Obsidian's %% and HTML's <!-- -->) are not mistaken for tasks or headings
+type markdownLines struct {
+	// fence is the opening fence of the current code block, if any
+	fence string
+	// comment is the delimiter closing the current comment, if any
+	comment     string
+	lineNumber  int
+	frontmatter bool
+}
+
+// next reports whether line, the next line of the note, is ordinary markdown
+// that may hold tasks and headings
+func (m *markdownLines) next(line string) bool {
+	m.lineNumber++
+
+	trimmed := strings.TrimSpace(line)
+
+	switch {
+	case m.lineNumber == 1 && strings.TrimRight(line, " \t\r") == "---":
+		m.frontmatter = true
+
+		return false
+	case m.frontmatter:
+		if trimmed == "---" || trimmed == "..." {
+			m.frontmatter = false
+		}
+
+		return false
+	case m.fence != "":
+		if isClosingFence(trimmed, m.fence) {
+			m.fence = ""
+		}
+
+		return false
+	case m.comment == "" && fenceRegex.MatchString(trimmed):
+		m.fence = fenceRegex.FindString(trimmed)
+
+		return false
+	}
+
+	// a line is ignored when it starts inside a comment or opens one; a
+	// comment later in the line (e.g. "- [ ] task %% note %%") leaves the
+	// task alone
+	ignored := m.comment != "" || strings.HasPrefix(trimmed, "%%") || strings.HasPrefix(trimmed, "<!--")
+	m.scanComments(line)
+
+	return !ignored
+}
+
+// scanComments follows comments opening and closing within line
+func (m *markdownLines) scanComments(line string) {
+	rest := line
+
+	for {
+		if m.comment != "" {
+			i := strings.Index(rest, m.comment)
+			if i < 0 {
+				return
+			}
+
+			rest = rest[i+len(m.comment):]
+			m.comment = ""
+
+			continue
+		}
+
+		i, j := strings.Index(rest, "%%"), strings.Index(rest, "<!--")
+
+		switch {
+		case i >= 0 && (j < 0 || i < j):
+			rest, m.comment = rest[i+len("%%"):], "%%"
+		case j >= 0:
+			rest, m.comment = rest[j+len("<!--"):], "-->"
+		default:
+			return
+		}
+	}
+}
+
+// isClosingFence reports whether line closes a code block opened by fence: a
+// run of the same character at least as long, and nothing else
+func isClosingFence(line, fence string) bool {
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
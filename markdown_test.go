@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []int // line numbers of ordinary markdown lines
+	}{
+		{
+			name:    "frontmatter",
+			content: "---\ntags: [a]\n- [ ] not a task\n---\n- [ ] task",
+			want:    []int{5},
+		},
+		{
+			name:    "frontmatter only on the first line",
+			content: "text\n---\n- [ ] task",
+			want:    []int{1, 2, 3},
+		},
+		{
+			name:    "backtick fence",
+			content: "```md\n- [ ] example\n```\n- [ ] task",
+			want:    []int{4},
+		},
+		{
+			name:    "tilde fence needs a matching closer",
+			content: "~~~~\n```\n~~~\n- [ ] example\n~~~~~\n- [ ] task",
+			want:    []int{6},
+		},
+		{
+			name:    "indented fence in a list",
+			content: "- item\n    ```\n    - [ ] example\n    ```\n- [ ] task",
+			want:    []int{1, 5},
+		},
+		{
+			name:    "obsidian comment block",
+			content: "%%\n- [ ] hidden\n%%\n- [ ] task",
+			want:    []int{4},
+		},
+		{
+			name:    "single line comments",
+			content: "%% - [ ] hidden %%\n<!-- - [ ] hidden -->\n- [ ] task %% note %%",
+			want:    []int{3},
+		},
+		{
+			name:    "comment opened at the end of a task",
+			content: "- [ ] task %%\n- [ ] hidden\n%% - [ ] hidden",
+			want:    []int{1},
+		},
+		{
+			name:    "html comment block",
+			content: "<!--\n- [ ] hidden\n%% not a delimiter here\n-->\n- [ ] task\n- [ ] task",
+			want:    []int{5, 6},
+		},
+		{
+			name:    "fence inside a comment",
+			content: "%%\n```\n%%\n- [ ] task",
+			want:    []int{4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				md  markdownLines
+				got []int
+			)
+
+			for i, line := range strings.Split(tt.content, "\n") {
+				if md.next(line) {
+					got = append(got, i+1)
+				}
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseTasksFromFileSkipsCodeAndComments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	require.NoError(t, os.WriteFile(path, []byte(`---
+title: "- [ ] in frontmatter"
+---
+# Real
+- [ ] first
+`+"```markdown"+`
+# Not a heading
+- [ ] in code
+`+"```"+`
+%% - [ ] in comment %%
+<!--
+- [ ] in html comment
+-->
+- [ ] second
+`), 0o600))
+
+	tasks, warnings, err := parseTasksFromFile(path, dir, nil)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "first", tasks[0].Description)
+	assert.Equal(t, "second", tasks[1].Description)
+	assert.Equal(t, 14, tasks[1].LineNumber)
+	assert.Equal(t, "Real", tasks[1].Heading)
+}
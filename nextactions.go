@@ -0,0 +1,225 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const defaultNextActionsLimit = 10
+
+type NextActionsInput struct {
+	RootDirs []string `json:"rootDirs,omitempty" jsonschema:"Directories to scan for markdown files. Each must be inside a configured -root directory. Defaults to all -root directories"`
+
+	Query string `json:"query,omitempty" jsonschema:"Tasks query filters narrowing the candidates, e.g. tag include #work. Sorting and pagination instructions are ignored"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum number of actions to return (default 10)"`
+}
+
+// NextAction is a ranked task with the reasons behind its score
+type NextAction struct {
+	Task *Task `json:"task"`
+	// Reasons explains each part of the score, e.g. "overdue by 2 days (+12.0)"
+	Reasons []string `json:"reasons"`
+	Rank    int      `json:"rank"`
+	Score   float64  `json:"score"`
+}
+
+type NextActionsOutput struct {
+	Actions     []NextAction     `json:"actions"`
+	QueryErrors []QueryLineError `json:"queryErrors,omitempty"`
+	// Total is the number of actionable tasks before the limit is applied
+	Total int `json:"total"`
+}
+
+func (s *taskServer) nextActions(ctx context.Context, _ *mcp.CallToolRequest, input NextActionsInput) (
+	*mcp.CallToolResult,
+	NextActionsOutput,
+	error,
+) {
+	empty := NextActionsOutput{Actions: []NextAction{}}
+
+	if input.Limit < 0 {
+		return errorResult("limit must not be negative"), empty, nil
+	}
+
+	roots, err := s.scanRoots(input.RootDirs)
+	if err != nil {
+		return errorResult(err.Error()), empty, nil
+	}
+
+	query, err := ParseQueryWithOptions(input.Query, s.queryOptions)
+
+	var qerr *QueryError
+	if errors.As(err, &qerr) {
+		empty.QueryErrors = qerr.Errors
+
+		return errorResult("invalid query: " + qerr.Error()), empty, nil
+	}
+
+	if err != nil {
+		return errorResult("failed to parse query: " + err.Error()), empty, nil
+	}
+
+	// only the filters apply; ranking replaces sorting and pagination
+	result, err := ScanVaultWithOptions(ctx, roots, &Query{Filters: query.Filters, Today: query.Today},
+		ScanOptions{Exclude: s.exclude, Index: s.index, Workers: s.workers})
+	if err != nil {
+		return errorResult("failed to scan tasks: " + err.Error()), empty, err
+	}
+
+	actions := rankNextActions(result.Tasks, today(s.queryOptions.Now, s.queryOptions.Location))
+
+	limit := input.Limit
+	if limit == 0 {
+		limit = defaultNextActionsLimit
+	}
+
+	return nil, NextActionsOutput{Actions: actions[:min(limit, len(actions))], Total: len(actions)}, nil
+}
+
+// rankNextActions returns the tasks that can be worked on today, best first:
+// those that are not done, not blocked by another task, and whose start date
+// (if any) has been reached
+func rankNextActions(tasks []*Task, day time.Time) []NextAction {
+	actions := make([]NextAction, 0, len(tasks))
+
+	for _, task := range tasks {
+		if task.StatusType.IsDone() || task.Blocked {
+			continue
+		}
+
+		if task.StartDate != "" && compareDates(task.StartDate, day.Format(dateLayout)) > 0 {
+			continue
+		}
+
+		score, reasons := scoreNextAction(task, day)
+		actions = append(actions, NextAction{Task: task, Score: score, Reasons: reasons})
+	}
+
+	slices.SortStableFunc(actions, func(a, b NextAction) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+
+		// earlier due dates first, then tasks without one
+		if c := compareOptionalDates(a.Task.DueDate, b.Task.DueDate); c != 0 {
+			return c
+		}
+
+		if c := cmp.Compare(a.Task.FilePath, b.Task.FilePath); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Task.LineNumber, b.Task.LineNumber)
+	})
+
+	for i := range actions {
+		actions[i].Rank = i + 1
+	}
+
+	return actions
+}
+
+// scoreNextAction scores a task by how pressing it is, returning an
+// explanation of each contribution to the score
+//
+//nolint:gocyclo // one branch per scoring rule
+func scoreNextAction(task *Task, day time.Time) (float64, []string) {
+	var (
+		score   float64
+		reasons = []string{}
+	)
+
+	add := func(points float64, format string, args ...any) {
+		score += points
+		reasons = append(reasons, fmt.Sprintf(format, args...)+fmt.Sprintf(" (%+.1f)", points))
+	}
+
+	if days, ok := daysUntil(task.DueDate, day); ok {
+		switch {
+		case days < 0:
+			add(12, "overdue by %s", pluralDays(-days))
+		case days == 0:
+			add(10, "due today")
+		case days <= 7:
+			add(float64(8-days), "due in %s", pluralDays(days))
+		default:
+			add(0, "due in %s", pluralDays(days))
+		}
+	}
+
+	if days, ok := daysUntil(task.ScheduledDate, day); ok {
+		switch {
+		case days < 0:
+			add(5, "scheduled %s ago", pluralDays(-days))
+		case days == 0:
+			add(5, "scheduled for today")
+		default:
+			add(-3, "scheduled for %s", task.ScheduledDate)
+		}
+	}
+
+	switch task.Priority {
+	case PriorityHighest:
+		add(9, "highest priority")
+	case PriorityHigh:
+		add(6, "high priority")
+	case PriorityMedium:
+		add(3, "medium priority")
+	case PriorityLow:
+		add(-1, "low priority")
+	case PriorityNone:
+	}
+
+	if task.StatusType == StatusTypeInProgress {
+		add(4, "already in progress")
+	}
+
+	if task.Blocking {
+		add(3, "other tasks are waiting on it")
+	}
+
+	if task.StartDate != "" {
+		add(0, "startable since %s", task.StartDate)
+	}
+
+	return score, reasons
+}
+
+// daysUntil returns the number of days from day until date, or false if
+// date is empty or invalid
+func daysUntil(date string, day time.Time) (int, bool) {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return 0, false
+	}
+
+	return int(t.Sub(day).Hours() / 24), true
+}
+
+func pluralDays(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+
+	return fmt.Sprintf("%d days", n)
+}
+
+// compareOptionalDates compares two dates, ordering empty dates last
+func compareOptionalDates(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	default:
+		return cmp.Compare(a, b)
+	}
+}
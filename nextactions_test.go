@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankNextActions(t *testing.T) {
+	day := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+	tasks := parseNested(`- [ ] Someday
+- [ ] Overdue 📅 2026-10-14
+- [x] Done already 📅 2026-10-01
+- [ ] Not startable yet 🛫 2026-10-20
+- [ ] Blocked ⛔ dep
+- [ ] Dependency 🆔 dep 🔼
+- [/] Started ⏳ 2026-10-16
+- [ ] Due tomorrow ⏫ 📅 2026-10-17
+- [ ] Low priority 🔽
+- [ ] Started earlier 🛫 2026-10-10`)
+	linkDependencies(tasks)
+
+	actions := rankNextActions(tasks, day)
+
+	got := make([]string, 0, len(actions))
+	for _, a := range actions {
+		got = append(got, a.Task.Description)
+	}
+
+	assert.Equal(t, []string{
+		"Due tomorrow",    // 7 + 6
+		"Overdue",         // 12
+		"Started",         // 5 + 4
+		"Dependency",      // 3 + 3
+		"Someday",         // 0
+		"Started earlier", // 0, later in the file
+		"Low priority",    // -1
+	}, got)
+
+	assert.Equal(t, 1, actions[0].Rank)
+	assert.InDelta(t, 13.0, actions[0].Score, 0.001)
+	assert.Equal(t, []string{"due in 1 day (+7.0)", "high priority (+6.0)"}, actions[0].Reasons)
+	assert.Equal(t, []string{"overdue by 2 days (+12.0)"}, actions[1].Reasons)
+	assert.Equal(t, []string{"scheduled for today (+5.0)", "already in progress (+4.0)"}, actions[2].Reasons)
+	assert.Equal(t, []string{"medium priority (+3.0)", "other tasks are waiting on it (+3.0)"}, actions[3].Reasons)
+	assert.Empty(t, actions[4].Reasons)
+}
+
+func TestNextActions(t *testing.T) {
+	vault := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "todo.md"), []byte(`- [ ] Later #work
+- [ ] Now #work 📅 2026-10-16
+- [ ] Home chore #home ⏫
+`), 0o600))
+
+	s := testServer(t, vault)
+
+	res, out, err := s.nextActions(t.Context(), nil, NextActionsInput{Query: "tag include #work\nlimit 1", Limit: 1})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	assert.Equal(t, 2, out.Total)
+	require.Len(t, out.Actions, 1)
+	assert.Equal(t, "Now", out.Actions[0].Task.Description)
+
+	res, out, err = s.nextActions(t.Context(), nil, NextActionsInput{Query: "tag includ #work"})
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.True(t, res.IsError)
+	assert.NotEmpty(t, out.QueryErrors)
+}
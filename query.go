@@ -3,9 +3,11 @@ package main
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // Filter is an interface for task filters
@@ -19,20 +21,41 @@ type SortField int
 const (
 	SortByPriority SortField = iota
 	SortByDue
+	SortByUrgency
+	SortByPath
+	SortByFilename
+	SortByDescription
+	SortByStatus
+	SortByTag
+	SortByScheduled
+	SortByStart
+	SortByDone
+	SortByCreated
+	SortByCancelled
+	SortByHappens
+	SortByHeading
 )
 
 // SortKey represents a sort key with direction
 type SortKey struct {
-	Field   SortField
-	Reverse bool
+	Field SortField
+	// TagIndex picks the tag to sort by for SortByTag, counting from 1
+	TagIndex int
+	Reverse  bool
 }
 
 // Query represents a parsed query with filters
 type Query struct {
 	Filters []Filter
 	SortBy  []SortKey
+	GroupBy []GroupKey
 	Limit   int // 0 means no limit
 	Offset  int // 0 means no offset
+	// GroupLimit caps the number of tasks listed in each group; 0 means no
+	// limit
+	GroupLimit int
+	// Today is the date relative dates and urgency are computed from
+	Today time.Time
 }
 
 // StatusFilter filters tasks by completion status
@@ -48,6 +71,31 @@ func (f *StatusFilter) Matches(task *Task) bool {
 	return task.Status == "incomplete"
 }
 
+// StatusTypeFilter filters tasks by status type
+type StatusTypeFilter struct {
+	Type StatusType
+	Is   bool
+}
+
+func (f *StatusTypeFilter) Matches(task *Task) bool {
+	return (task.StatusType == f.Type) == f.Is
+}
+
+// StatusNameFilter filters tasks by status name (case-insensitive)
+type StatusNameFilter struct {
+	Substring string
+	Include   bool
+}
+
+func (f *StatusNameFilter) Matches(task *Task) bool {
+	contains := containsFold(task.StatusName, f.Substring)
+	if f.Include {
+		return contains
+	}
+
+	return !contains
+}
+
 // DueDateOp represents a due date comparison operation
 type DueDateOp int
 
@@ -57,39 +105,75 @@ const (
 	DueOpOnOrAfter
 	DueOpNone
 	DueOpHas
+	DueOpBefore
+	DueOpAfter
 )
 
-// DueDateFilter filters tasks by due date
+// DueDateFilter filters tasks by due date. Date and End bound an inclusive
+// range of days; End may be empty for a single day.
 type DueDateFilter struct {
 	Date string
+	End  string
 	Op   DueDateOp
 }
 
+func (f *DueDateFilter) end() string {
+	if f.End == "" {
+		return f.Date
+	}
+
+	return f.End
+}
+
 func (f *DueDateFilter) Matches(task *Task) bool {
 	switch f.Op {
 	case DueOpNone:
 		return task.DueDate == ""
 	case DueOpHas:
 		return task.DueDate != ""
+	}
+
+	if task.DueDate == "" {
+		return false
+	}
+
+	switch f.Op {
 	case DueOpOn:
-		return task.DueDate == f.Date
+		return compareDates(task.DueDate, f.Date) >= 0 && compareDates(task.DueDate, f.end()) <= 0
 	case DueOpOnOrBefore:
-		if task.DueDate == "" {
-			return false
-		}
-
-		return compareDates(task.DueDate, f.Date) <= 0
+		return compareDates(task.DueDate, f.end()) <= 0
 	case DueOpOnOrAfter:
-		if task.DueDate == "" {
-			return false
-		}
-
 		return compareDates(task.DueDate, f.Date) >= 0
+	case DueOpBefore:
+		return compareDates(task.DueDate, f.Date) < 0
+	case DueOpAfter:
+		return compareDates(task.DueDate, f.end()) > 0
 	default:
 		return false
 	}
 }
 
+// containsFold reports whether substr is within s, ignoring case under
+// Unicode simple case folding (as strings.EqualFold does)
+func containsFold(s, substr string) bool {
+	return strings.Contains(foldCase(s), foldCase(substr))
+}
+
+// foldCase maps every rune of s to a canonical member of its simple case
+// folding orbit, so that strings differing only in case fold to the same
+// string. Simple folding maps runes one to one, so unlike strings.ToLower it
+// also unifies runes such as 'K' and the Kelvin sign, or 'σ' and 'ς'.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		folded := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			folded = min(folded, f)
+		}
+
+		return folded
+	}, s)
+}
+
 func compareDates(date1, date2 string) int {
 	t1, err1 := time.Parse("2006-01-02", date1)
 
@@ -109,11 +193,13 @@ func compareDates(date1, date2 string) int {
 	return 0
 }
 
-// TagFilter filters tasks by tags
+// TagFilter filters tasks by tags (case-insensitive). Unless Exact is set, a
+// tag also matches its nested tags, so "work" matches "work/clientA".
 type TagFilter struct {
 	Tag     string
 	Include bool
 	HasAny  bool
+	Exact   bool
 }
 
 func (f *TagFilter) Matches(task *Task) bool {
@@ -130,7 +216,7 @@ func (f *TagFilter) Matches(task *Task) bool {
 
 	// "tag include #tag" or "tag do not include #tag"
 	for _, tag := range task.Tags {
-		if tag == f.Tag {
+		if f.matchesTag(tag) {
 			return f.Include
 		}
 	}
@@ -138,14 +224,33 @@ func (f *TagFilter) Matches(task *Task) bool {
 	return !f.Include
 }
 
-// PathFilter filters tasks by file path
+func (f *TagFilter) matchesTag(tag string) bool {
+	if strings.EqualFold(tag, f.Tag) {
+		return true
+	}
+
+	if f.Exact {
+		return false
+	}
+
+	// nested tags: "work" matches "work/clientA" and "work/clientA/site"
+	for i, c := range tag {
+		if c == '/' && strings.EqualFold(tag[:i], f.Tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PathFilter filters tasks by file path (case-insensitive)
 type PathFilter struct {
 	Substring string
 	Include   bool
 }
 
 func (f *PathFilter) Matches(task *Task) bool {
-	contains := strings.Contains(task.FilePath, f.Substring)
+	contains := containsFold(task.FilePath, f.Substring)
 	if f.Include {
 		return contains
 	}
@@ -153,14 +258,14 @@ func (f *PathFilter) Matches(task *Task) bool {
 	return !contains
 }
 
-// DescriptionFilter filters tasks by description
+// DescriptionFilter filters tasks by description (case-insensitive)
 type DescriptionFilter struct {
 	Substring string
 	Include   bool
 }
 
 func (f *DescriptionFilter) Matches(task *Task) bool {
-	contains := strings.Contains(task.Description, f.Substring)
+	contains := containsFold(task.Description, f.Substring)
 	if f.Include {
 		return contains
 	}
@@ -168,18 +273,127 @@ func (f *DescriptionFilter) Matches(task *Task) bool {
 	return !contains
 }
 
+// RegexField is a task field matched by a RegexFilter
+type RegexField int
+
+const (
+	RegexDescription RegexField = iota
+	RegexPath
+	RegexTag
+	RegexHeading
+)
+
+// RegexFilter filters tasks by matching a field against a regular
+// expression. Tags are matched with their leading #, and a task matches if
+// any of its tags do.
+type RegexFilter struct {
+	Pattern *regexp.Regexp
+	Field   RegexField
+	Match   bool
+}
+
+func (f *RegexFilter) Matches(task *Task) bool {
+	var matched bool
+
+	switch f.Field {
+	case RegexDescription:
+		matched = f.Pattern.MatchString(task.Description)
+	case RegexPath:
+		matched = f.Pattern.MatchString(task.FilePath)
+	case RegexHeading:
+		matched = f.Pattern.MatchString(task.Heading)
+	case RegexTag:
+		matched = slices.ContainsFunc(task.Tags, func(tag string) bool {
+			return f.Pattern.MatchString("#" + tag)
+		})
+	}
+
+	return matched == f.Match
+}
+
+// RecurringFilter filters tasks by whether they have a recurrence rule
+type RecurringFilter struct {
+	Recurring bool
+}
+
+func (f *RecurringFilter) Matches(task *Task) bool {
+	return (task.Recurrence != "") == f.Recurring
+}
+
+// SubItemFilter excludes tasks nested under another list item
+type SubItemFilter struct{}
+
+func (f *SubItemFilter) Matches(task *Task) bool {
+	return task.ParentID == ""
+}
+
+// ChildrenFilter filters tasks by whether they have subtasks
+type ChildrenFilter struct {
+	Has bool
+}
+
+func (f *ChildrenFilter) Matches(task *Task) bool {
+	return (len(task.ChildIDs) > 0) == f.Has
+}
+
+// DependencyFilter filters tasks by their place in the dependency graph
+type DependencyFilter struct {
+	// Blocking selects tasks that block others rather than blocked tasks
+	Blocking bool
+	Is       bool
+}
+
+func (f *DependencyFilter) Matches(task *Task) bool {
+	if f.Blocking {
+		return task.Blocking == f.Is
+	}
+
+	return task.Blocked == f.Is
+}
+
+// UrgencyFilter filters tasks by urgency score
+type UrgencyFilter struct {
+	Threshold float64
+	Above     bool
+}
+
+func (f *UrgencyFilter) Matches(task *Task) bool {
+	if f.Above {
+		return task.Urgency > f.Threshold
+	}
+
+	return task.Urgency < f.Threshold
+}
+
 var (
 	statusDoneRegex    = regexp.MustCompile(`^done$`)
 	statusNotDoneRegex = regexp.MustCompile(`^not done$`)
 
-	dueOnRegex         = regexp.MustCompile(`^due on (\d{4}-\d{2}-\d{2})$`)
-	dueOnOrBeforeRegex = regexp.MustCompile(`^due on or before (\d{4}-\d{2}-\d{2})$`)
-	dueOnOrAfterRegex  = regexp.MustCompile(`^due on or after (\d{4}-\d{2}-\d{2})$`)
-	dueNoneRegex       = regexp.MustCompile(`^no due date$`)
-	dueHasRegex        = regexp.MustCompile(`^has due date$`)
+	statusTypeIsRegex          = regexp.MustCompile(`^status\.type is (\S+)$`)
+	statusTypeIsNotRegex       = regexp.MustCompile(`^status\.type is not (\S+)$`)
+	statusNameIncludesRegex    = regexp.MustCompile(`^status\.name includes (.+)$`)
+	statusNameNotIncludesRegex = regexp.MustCompile(`^status\.name does not include (.+)$`)
 
-	tagIncludeRegex    = regexp.MustCompile(`^tags? include #([\w-]+)$`)
-	tagNotIncludeRegex = regexp.MustCompile(`^tags? do not include #([\w-]+)$`)
+	dueRegex     = regexp.MustCompile(`^due(?: (on or before|on or after|before|after|on|in))? (.+)$`)
+	dueNoneRegex = regexp.MustCompile(`^no due date$`)
+	dueHasRegex  = regexp.MustCompile(`^has due date$`)
+
+	recurringRegex    = regexp.MustCompile(`^is recurring$`)
+	notRecurringRegex = regexp.MustCompile(`^is not recurring$`)
+
+	excludeSubItemsRegex = regexp.MustCompile(`^exclude sub-items$`)
+	hasChildrenRegex     = regexp.MustCompile(`^has children$`)
+	noChildrenRegex      = regexp.MustCompile(`^no children$`)
+
+	blockedRegex     = regexp.MustCompile(`^is blocked$`)
+	notBlockedRegex  = regexp.MustCompile(`^is not blocked$`)
+	blockingRegex    = regexp.MustCompile(`^is blocking$`)
+	notBlockingRegex = regexp.MustCompile(`^is not blocking$`)
+
+	urgencyRegex = regexp.MustCompile(`^urgency (above|below) (-?\d+(?:\.\d+)?)$`)
+
+	tagIncludeRegex    = regexp.MustCompile(`^tags? include (exactly )?#(` + tagPattern + `)$`)
+	tagNotIncludeRegex = regexp.MustCompile(`^tags? do not include (exactly )?#(` + tagPattern + `)$`)
 	tagHasRegex        = regexp.MustCompile(`^has tags$`)
 	tagNoRegex         = regexp.MustCompile(`^no tags$`)
 
@@ -189,20 +403,53 @@ var (
 	descIncludesRegex    = regexp.MustCompile(`^description includes (.+)$`)
 	descNotIncludesRegex = regexp.MustCompile(`^description does not include (.+)$`)
 
-	sortByRegex = regexp.MustCompile(`^sort by (priority|due)(?: (reverse))?$`)
+	regexFilterRegex = regexp.MustCompile(`^(description|path|tags?|heading) regex (matches|does not match) (.+)$`)
+
+	// the Tasks plugin writes "sort by tag reverse 2"; "sort by tag 2 reverse"
+	// is accepted too
+	sortByRegex = regexp.MustCompile(`^sort by (\S+)(?: (\d+))?(?: (reverse))?(?: (\d+))?$`)
+
+	groupByRegex     = regexp.MustCompile(`^group by (\S+)(?: (reverse))?$`)
+	limitGroupsRegex = regexp.MustCompile(`^limit groups(?: to)? (\d+)(?: tasks?)?$`)
 
 	limitRegex  = regexp.MustCompile(`^limit (\d+)$`)
 	offsetRegex = regexp.MustCompile(`^offset (\d+)$`)
 )
 
-// ParseQuery parses a query string into a Query struct
-//
-//nolint:gocyclo,funlen // complexity from parsing many filter/sort/pagination line types
+// QueryOptions configures how a query string is parsed
+type QueryOptions struct {
+	// Now returns the current time, used to resolve relative dates such as
+	// "today". Defaults to time.Now.
+	Now func() time.Time
+	// Location is the timezone relative dates are resolved in. Defaults to
+	// time.Local.
+	Location *time.Location
+	// Lenient skips unknown lines instead of reporting them as errors.
+	// Malformed lines (e.g. invalid dates) are still errors.
+	Lenient bool
+}
+
+// queryParser holds the state needed to parse individual query lines
+type queryParser struct {
+	today time.Time
+}
+
+// ParseQuery parses a query string into a Query struct, resolving relative
+// dates against the current local time
 func ParseQuery(queryStr string) (*Query, error) {
-	query := &Query{Filters: []Filter{}}
+	return ParseQueryWithOptions(queryStr, QueryOptions{})
+}
 
-	lines := strings.SplitSeq(queryStr, "\n")
-	for line := range lines {
+// ParseQueryWithOptions parses a query string into a Query struct. Every
+// unknown or malformed line is reported in the returned *QueryError; with
+// opts.Lenient set, unknown lines are skipped instead.
+func ParseQueryWithOptions(queryStr string, opts QueryOptions) (*Query, error) {
+	p := &queryParser{today: today(opts.Now, opts.Location)}
+	query := &Query{Filters: []Filter{}, Today: p.today}
+
+	var lineErrors []QueryLineError
+
+	for i, line := range strings.Split(queryStr, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -213,60 +460,148 @@ func ParseQuery(queryStr string) (*Query, error) {
 			continue
 		}
 
-		if matches := sortByRegex.FindStringSubmatch(line); len(matches) >= 2 {
-			key := SortKey{Reverse: matches[2] == "reverse"}
+		known, err := p.parseLine(query, line)
+
+		switch {
+		case err != nil:
+			lineErrors = append(lineErrors, QueryLineError{
+				Line:    i + 1,
+				Text:    line,
+				Message: err.Error(),
+			})
+		case !known && !opts.Lenient:
+			lineErrors = append(lineErrors, QueryLineError{
+				Line:       i + 1,
+				Text:       line,
+				Message:    "unknown instruction",
+				Suggestion: suggestInstruction(line),
+			})
+		}
+	}
 
-			switch matches[1] {
-			case "priority":
-				key.Field = SortByPriority
-			case "due":
-				key.Field = SortByDue
-			}
+	if len(lineErrors) > 0 {
+		return nil, &QueryError{Errors: lineErrors}
+	}
 
-			query.SortBy = append(query.SortBy, key)
+	return query, nil
+}
 
-			continue
+// parseSortKey parses the field name, optional tag index and direction of a
+// "sort by" line
+func parseSortKey(name, index string, reverse bool) (SortKey, error) {
+	field, ok := sortFields()[name]
+	if !ok {
+		return SortKey{}, fmt.Errorf("unknown sort field %q", name)
+	}
+
+	key := SortKey{Field: field, Reverse: reverse}
+
+	switch {
+	case field == SortByTag && index == "":
+		key.TagIndex = 1
+	case field == SortByTag:
+		n, err := strconv.Atoi(index)
+		if err != nil || n < 1 {
+			return SortKey{}, fmt.Errorf("invalid tag number %q", index)
 		}
 
-		if matches := limitRegex.FindStringSubmatch(line); len(matches) >= 2 {
-			n, err := strconv.Atoi(matches[1])
-			if err != nil {
-				return nil, fmt.Errorf("invalid limit value %q: %w", matches[1], err)
-			}
+		key.TagIndex = n
+	case index != "":
+		return SortKey{}, fmt.Errorf("only tag sorting takes a number, not %s", name)
+	}
 
-			query.Limit = n
+	return key, nil
+}
 
-			continue
+// parseLine parses a single query line into query, reporting whether the line
+// was recognized
+//
+//nolint:gocyclo // complexity from parsing many filter/sort/pagination line types
+func (p *queryParser) parseLine(query *Query, line string) (bool, error) {
+	if matches := sortByRegex.FindStringSubmatch(line); len(matches) >= 5 && (matches[2] == "" || matches[4] == "") {
+		if _, ok := sortFields()[matches[1]]; !ok {
+			// reported as an unknown instruction, with a suggestion
+			return false, nil
 		}
 
-		if matches := offsetRegex.FindStringSubmatch(line); len(matches) >= 2 {
-			n, err := strconv.Atoi(matches[1])
-			if err != nil {
-				return nil, fmt.Errorf("invalid offset value %q: %w", matches[1], err)
-			}
+		key, err := parseSortKey(matches[1], matches[2]+matches[4], matches[3] == "reverse")
+		if err != nil {
+			return true, err
+		}
 
-			query.Offset = n
+		query.SortBy = append(query.SortBy, key)
 
-			continue
+		return true, nil
+	}
+
+	if matches := groupByRegex.FindStringSubmatch(line); len(matches) >= 3 {
+		field, ok := groupFields()[matches[1]]
+		if !ok {
+			// reported as an unknown instruction, with a suggestion
+			return false, nil
 		}
 
-		filter, err := parseFilterLine(line)
+		query.GroupBy = append(query.GroupBy, GroupKey{Field: field, Reverse: matches[2] == "reverse"})
+
+		return true, nil
+	}
+
+	if matches := limitGroupsRegex.FindStringSubmatch(line); len(matches) >= 2 {
+		n, err := strconv.Atoi(matches[1])
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse filter line %q: %w", line, err)
+			return true, fmt.Errorf("invalid group limit value %q: %w", matches[1], err)
 		}
 
-		if filter != nil {
-			query.Filters = append(query.Filters, filter)
+		query.GroupLimit = n
+
+		return true, nil
+	}
+
+	if matches := limitRegex.FindStringSubmatch(line); len(matches) >= 2 {
+		n, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid limit value %q: %w", matches[1], err)
 		}
+
+		query.Limit = n
+
+		return true, nil
 	}
 
-	return query, nil
+	if matches := offsetRegex.FindStringSubmatch(line); len(matches) >= 2 {
+		n, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return true, fmt.Errorf("invalid offset value %q: %w", matches[1], err)
+		}
+
+		query.Offset = n
+
+		return true, nil
+	}
+
+	filter, err := p.parseFilterLine(line)
+	if err != nil {
+		return true, err
+	}
+
+	if filter == nil {
+		return false, nil
+	}
+
+	query.Filters = append(query.Filters, filter)
+
+	return true, nil
 }
 
-//nolint:gocyclo,funlen,unparam // parsing different filter types requires branching
-func parseFilterLine(line string) (Filter, error) {
+//nolint:gocyclo,funlen // parsing different filter types requires branching
+func (p *queryParser) parseFilterLine(line string) (Filter, error) {
 	line = strings.TrimSpace(line)
 
+	// Boolean combinations of filters
+	if isBooleanLine(line) {
+		return parseBooleanLine(line, p.parseBooleanOperand)
+	}
+
 	// Status filters
 	if statusDoneRegex.MatchString(line) {
 		return &StatusFilter{Done: true}, nil
@@ -276,17 +611,40 @@ func parseFilterLine(line string) (Filter, error) {
 		return &StatusFilter{Done: false}, nil
 	}
 
-	// Due date filters
-	if matches := dueOnRegex.FindStringSubmatch(line); len(matches) >= 2 {
-		return &DueDateFilter{Op: DueOpOn, Date: matches[1]}, nil
+	if matches := statusTypeIsNotRegex.FindStringSubmatch(line); len(matches) >= 2 {
+		statusType, err := parseStatusType(matches[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return &StatusTypeFilter{Type: statusType, Is: false}, nil
+	}
+
+	if matches := statusTypeIsRegex.FindStringSubmatch(line); len(matches) >= 2 {
+		statusType, err := parseStatusType(matches[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return &StatusTypeFilter{Type: statusType, Is: true}, nil
 	}
 
-	if matches := dueOnOrBeforeRegex.FindStringSubmatch(line); len(matches) >= 2 {
-		return &DueDateFilter{Op: DueOpOnOrBefore, Date: matches[1]}, nil
+	if matches := statusNameIncludesRegex.FindStringSubmatch(line); len(matches) >= 2 {
+		return &StatusNameFilter{Include: true, Substring: matches[1]}, nil
 	}
 
-	if matches := dueOnOrAfterRegex.FindStringSubmatch(line); len(matches) >= 2 {
-		return &DueDateFilter{Op: DueOpOnOrAfter, Date: matches[1]}, nil
+	if matches := statusNameNotIncludesRegex.FindStringSubmatch(line); len(matches) >= 2 {
+		return &StatusNameFilter{Include: false, Substring: matches[1]}, nil
+	}
+
+	// Due date filters
+	if matches := dueRegex.FindStringSubmatch(line); len(matches) >= 3 {
+		r, err := parseDateRange(matches[2], p.today)
+		if err != nil {
+			return nil, err
+		}
+
+		return &DueDateFilter{Op: dueDateOp(matches[1]), Date: r.Start, End: r.End}, nil
 	}
 
 	if dueNoneRegex.MatchString(line) {
@@ -297,13 +655,62 @@ func parseFilterLine(line string) (Filter, error) {
 		return &DueDateFilter{Op: DueOpHas}, nil
 	}
 
+	// Recurrence filters
+	if recurringRegex.MatchString(line) {
+		return &RecurringFilter{Recurring: true}, nil
+	}
+
+	if notRecurringRegex.MatchString(line) {
+		return &RecurringFilter{Recurring: false}, nil
+	}
+
+	// Hierarchy filters
+	if excludeSubItemsRegex.MatchString(line) {
+		return &SubItemFilter{}, nil
+	}
+
+	if hasChildrenRegex.MatchString(line) {
+		return &ChildrenFilter{Has: true}, nil
+	}
+
+	if noChildrenRegex.MatchString(line) {
+		return &ChildrenFilter{Has: false}, nil
+	}
+
+	// Dependency filters
+	if blockedRegex.MatchString(line) {
+		return &DependencyFilter{Is: true}, nil
+	}
+
+	if notBlockedRegex.MatchString(line) {
+		return &DependencyFilter{Is: false}, nil
+	}
+
+	if blockingRegex.MatchString(line) {
+		return &DependencyFilter{Blocking: true, Is: true}, nil
+	}
+
+	if notBlockingRegex.MatchString(line) {
+		return &DependencyFilter{Blocking: true, Is: false}, nil
+	}
+
+	// Urgency filters
+	if matches := urgencyRegex.FindStringSubmatch(line); len(matches) >= 3 {
+		threshold, err := strconv.ParseFloat(matches[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid urgency %q: %w", matches[2], err)
+		}
+
+		return &UrgencyFilter{Threshold: threshold, Above: matches[1] == "above"}, nil
+	}
+
 	// Tag filters
-	if matches := tagIncludeRegex.FindStringSubmatch(line); len(matches) >= 2 {
-		return &TagFilter{Include: true, Tag: matches[1]}, nil
+	if matches := tagIncludeRegex.FindStringSubmatch(line); len(matches) >= 3 {
+		return &TagFilter{Include: true, Tag: matches[2], Exact: matches[1] != ""}, nil
 	}
 
-	if matches := tagNotIncludeRegex.FindStringSubmatch(line); len(matches) >= 2 {
-		return &TagFilter{Include: false, Tag: matches[1]}, nil
+	if matches := tagNotIncludeRegex.FindStringSubmatch(line); len(matches) >= 3 {
+		return &TagFilter{Include: false, Tag: matches[2], Exact: matches[1] != ""}, nil
 	}
 
 	if tagHasRegex.MatchString(line) {
@@ -332,10 +739,104 @@ func parseFilterLine(line string) (Filter, error) {
 		return &DescriptionFilter{Include: false, Substring: matches[1]}, nil
 	}
 
-	// Unknown filter - return nil to skip
+	// Regex filters
+	if matches := regexFilterRegex.FindStringSubmatch(line); len(matches) >= 4 {
+		pattern, err := compileRegexLiteral(matches[3])
+		if err != nil {
+			return nil, err
+		}
+
+		return &RegexFilter{Field: regexField(matches[1]), Pattern: pattern, Match: matches[2] == "matches"}, nil
+	}
+
+	// Unknown filter - the caller decides whether this is an error
 	return nil, nil
 }
 
+func regexField(name string) RegexField {
+	switch name {
+	case "path":
+		return RegexPath
+	case "tag", "tags":
+		return RegexTag
+	case "heading":
+		return RegexHeading
+	default:
+		return RegexDescription
+	}
+}
+
+// compileRegexLiteral compiles a JavaScript-style regular expression literal
+// such as /^buy/i, as used by the Tasks plugin. The i, m, s and u flags
+// are supported.
+func compileRegexLiteral(literal string) (*regexp.Regexp, error) {
+	end := strings.LastIndex(literal, "/")
+	if !strings.HasPrefix(literal, "/") || end < 1 {
+		return nil, fmt.Errorf("regex must be enclosed in slashes, like /%s/", literal)
+	}
+
+	pattern, flags := literal[1:end], literal[end+1:]
+	if pattern == "" {
+		return nil, fmt.Errorf("empty regex %s", literal)
+	}
+
+	goFlags := ""
+
+	for _, flag := range flags {
+		switch flag {
+		case 'i', 'm', 's':
+			if !strings.ContainsRune(goFlags, flag) {
+				goFlags += string(flag)
+			}
+		case 'u':
+			// Go regexps always match Unicode
+		default:
+			return nil, fmt.Errorf("unsupported regex flag %q in %s", flag, literal)
+		}
+	}
+
+	if goFlags != "" {
+		pattern = "(?" + goFlags + ")" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %s: %w", literal, err)
+	}
+
+	return re, nil
+}
+
+func dueDateOp(op string) DueDateOp {
+	switch op {
+	case "before":
+		return DueOpBefore
+	case "after":
+		return DueOpAfter
+	case "on or before":
+		return DueOpOnOrBefore
+	case "on or after":
+		return DueOpOnOrAfter
+	default:
+		return DueOpOn
+	}
+}
+
+// parseBooleanOperand parses a single delimited sub-filter of a boolean line,
+// which may itself be a boolean expression
+func (p *queryParser) parseBooleanOperand(text string) (Filter, error) {
+	filter, err := p.parseFilterLine(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter == nil {
+		return nil, fmt.Errorf("unknown filter %q", text)
+	}
+
+	return filter, nil
+}
+
 // Matches checks if a task matches all filters in the query
 func (q *Query) Matches(task *Task) bool {
 	for _, filter := range q.Filters {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,6 +33,62 @@ func TestParseQuery(t *testing.T) {
 				assert.IsType(t, &StatusFilter{}, q.Filters[0])
 			},
 		},
+		{
+			name:    "is recurring",
+			query:   "is recurring\nis not recurring",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				require.Len(t, q.Filters, 2)
+				assert.Equal(t, &RecurringFilter{Recurring: true}, q.Filters[0])
+				assert.Equal(t, &RecurringFilter{Recurring: false}, q.Filters[1])
+			},
+		},
+		{
+			name:    "hierarchy filters",
+			query:   "exclude sub-items\nhas children\nno children",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				require.Len(t, q.Filters, 3)
+				assert.Equal(t, &SubItemFilter{}, q.Filters[0])
+				assert.Equal(t, &ChildrenFilter{Has: true}, q.Filters[1])
+				assert.Equal(t, &ChildrenFilter{Has: false}, q.Filters[2])
+			},
+		},
+		{
+			name:    "urgency",
+			query:   "urgency above 10\nurgency below -1.5\nsort by urgency",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				require.Len(t, q.Filters, 2)
+				assert.Equal(t, &UrgencyFilter{Threshold: 10, Above: true}, q.Filters[0])
+				assert.Equal(t, &UrgencyFilter{Threshold: -1.5, Above: false}, q.Filters[1])
+				assert.Equal(t, []SortKey{{Field: SortByUrgency}}, q.SortBy)
+			},
+		},
+		{
+			name:    "group by",
+			query:   "group by folder\ngroup by due reverse\nlimit groups to 5 tasks",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				assert.Equal(t, []GroupKey{{Field: GroupByFolder}, {Field: GroupByDue, Reverse: true}}, q.GroupBy)
+				assert.Equal(t, 5, q.GroupLimit)
+				assert.Empty(t, q.Filters)
+			},
+		},
+		{
+			name:    "limit groups without to",
+			query:   "limit groups 3",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				assert.Equal(t, 3, q.GroupLimit)
+				assert.Zero(t, q.Limit)
+			},
+		},
+		{
+			name:    "unknown group field",
+			query:   "group by colour",
+			wantErr: true,
+		},
 		{
 			name:    "status not done",
 			query:   "not done",
@@ -41,6 +98,47 @@ func TestParseQuery(t *testing.T) {
 				assert.IsType(t, &StatusFilter{}, q.Filters[0])
 			},
 		},
+		{
+			name:    "status type is",
+			query:   "status.type is in_progress",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				require.Len(t, q.Filters, 1)
+				f, ok := q.Filters[0].(*StatusTypeFilter)
+				require.True(t, ok)
+				assert.True(t, f.Is)
+				assert.Equal(t, StatusTypeInProgress, f.Type)
+			},
+		},
+		{
+			name:    "status type is not",
+			query:   "status.type is not DONE",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				require.Len(t, q.Filters, 1)
+				f, ok := q.Filters[0].(*StatusTypeFilter)
+				require.True(t, ok)
+				assert.False(t, f.Is)
+				assert.Equal(t, StatusTypeDone, f.Type)
+			},
+		},
+		{
+			name:    "unknown status type is an error",
+			query:   "status.type is SOMEDAY",
+			wantErr: true,
+		},
+		{
+			name:    "status name includes",
+			query:   "status.name includes progress",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				require.Len(t, q.Filters, 1)
+				f, ok := q.Filters[0].(*StatusNameFilter)
+				require.True(t, ok)
+				assert.True(t, f.Include)
+				assert.Equal(t, "progress", f.Substring)
+			},
+		},
 		{
 			name:    "due date on",
 			query:   "due on 2024-01-15",
@@ -83,6 +181,24 @@ func TestParseQuery(t *testing.T) {
 				assert.Equal(t, "my-tag", f.Tag)
 			},
 		},
+		{
+			name:    "tag include with nested tag",
+			query:   "tags include #work/clientA",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				require.Len(t, q.Filters, 1)
+				assert.Equal(t, &TagFilter{Include: true, Tag: "work/clientA"}, q.Filters[0])
+			},
+		},
+		{
+			name:    "tag do not include exactly",
+			query:   "tags do not include exactly #été",
+			wantErr: false,
+			check: func(t *testing.T, q *Query) {
+				require.Len(t, q.Filters, 1)
+				assert.Equal(t, &TagFilter{Include: false, Tag: "été", Exact: true}, q.Filters[0])
+			},
+		},
 		{
 			name:    "tag do not include with hyphenated tag",
 			query:   "tag do not include #my-tag",
@@ -187,20 +303,14 @@ func TestParseQuery(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:    "negative limit is ignored as unknown line",
+			name:    "negative limit is an unknown line",
 			query:   "limit -1",
-			wantErr: false,
-			check: func(t *testing.T, q *Query) {
-				assert.Equal(t, 0, q.Limit)
-			},
+			wantErr: true,
 		},
 		{
-			name:    "negative offset is ignored as unknown line",
+			name:    "negative offset is an unknown line",
 			query:   "offset -1",
-			wantErr: false,
-			check: func(t *testing.T, q *Query) {
-				assert.Equal(t, 0, q.Offset)
-			},
+			wantErr: true,
 		},
 		{
 			name:    "sort by priority",
@@ -272,6 +382,42 @@ func TestParseQuery(t *testing.T) {
 	}
 }
 
+func TestParseQueryRelativeDueDates(t *testing.T) {
+	opts := QueryOptions{
+		Now:      func() time.Time { return time.Date(2026, time.October, 16, 9, 0, 0, 0, time.UTC) },
+		Location: time.UTC,
+	}
+
+	tests := []struct {
+		query string
+		want  DueDateFilter
+	}{
+		{query: "due today", want: DueDateFilter{Op: DueOpOn, Date: "2026-10-16", End: "2026-10-16"}},
+		{query: "due before tomorrow", want: DueDateFilter{Op: DueOpBefore, Date: "2026-10-17", End: "2026-10-17"}},
+		{query: "due after next monday", want: DueDateFilter{Op: DueOpAfter, Date: "2026-10-19", End: "2026-10-19"}},
+		{query: "due in this week", want: DueDateFilter{Op: DueOpOn, Date: "2026-10-12", End: "2026-10-18"}},
+		{query: "due 2026-10-01 2026-10-31", want: DueDateFilter{Op: DueOpOn, Date: "2026-10-01", End: "2026-10-31"}},
+		{query: "due in 2026-W42", want: DueDateFilter{Op: DueOpOn, Date: "2026-10-12", End: "2026-10-18"}},
+		{query: "due on or before 2024-01-15", want: DueDateFilter{Op: DueOpOnOrBefore, Date: "2024-01-15", End: "2024-01-15"}},
+		{query: "due on or after last week", want: DueDateFilter{Op: DueOpOnOrAfter, Date: "2026-10-05", End: "2026-10-11"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQueryWithOptions(tt.query, opts)
+			require.NoError(t, err)
+			require.Len(t, q.Filters, 1)
+
+			f, ok := q.Filters[0].(*DueDateFilter)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, *f)
+		})
+	}
+
+	_, err := ParseQueryWithOptions("due before someday", opts)
+	require.Error(t, err)
+}
+
 func TestStatusFilter(t *testing.T) {
 	tests := []struct {
 		filter *StatusFilter
@@ -313,6 +459,67 @@ func TestStatusFilter(t *testing.T) {
 	}
 }
 
+func TestStatusTypeFilter(t *testing.T) {
+	inProgress := &Task{StatusType: StatusTypeInProgress}
+	todo := &Task{StatusType: StatusTypeTodo}
+
+	assert.True(t, (&StatusTypeFilter{Type: StatusTypeInProgress, Is: true}).Matches(inProgress))
+	assert.False(t, (&StatusTypeFilter{Type: StatusTypeInProgress, Is: true}).Matches(todo))
+	assert.False(t, (&StatusTypeFilter{Type: StatusTypeInProgress, Is: false}).Matches(inProgress))
+	assert.True(t, (&StatusTypeFilter{Type: StatusTypeInProgress, Is: false}).Matches(todo))
+}
+
+func TestStatusNameFilter(t *testing.T) {
+	task := &Task{StatusName: "In Progress"}
+
+	assert.True(t, (&StatusNameFilter{Include: true, Substring: "progress"}).Matches(task))
+	assert.False(t, (&StatusNameFilter{Include: true, Substring: "done"}).Matches(task))
+	assert.False(t, (&StatusNameFilter{Include: false, Substring: "Progress"}).Matches(task))
+	assert.True(t, (&StatusNameFilter{Include: false, Substring: "done"}).Matches(task))
+}
+
+func TestRecurringFilter(t *testing.T) {
+	recurring := &Task{Recurrence: "every day"}
+	once := &Task{}
+
+	assert.True(t, (&RecurringFilter{Recurring: true}).Matches(recurring))
+	assert.False(t, (&RecurringFilter{Recurring: true}).Matches(once))
+	assert.False(t, (&RecurringFilter{Recurring: false}).Matches(recurring))
+	assert.True(t, (&RecurringFilter{Recurring: false}).Matches(once))
+}
+
+func TestHierarchyFilters(t *testing.T) {
+	parent := &Task{ChildIDs: []string{"todo.md:2"}}
+	child := &Task{ParentID: "todo.md:1"}
+
+	assert.True(t, (&SubItemFilter{}).Matches(parent))
+	assert.False(t, (&SubItemFilter{}).Matches(child))
+	assert.True(t, (&ChildrenFilter{Has: true}).Matches(parent))
+	assert.False(t, (&ChildrenFilter{Has: true}).Matches(child))
+	assert.True(t, (&ChildrenFilter{Has: false}).Matches(child))
+}
+
+func TestDependencyFilter(t *testing.T) {
+	blocked := &Task{Blocked: true}
+	blocking := &Task{Blocking: true}
+
+	assert.True(t, (&DependencyFilter{Is: true}).Matches(blocked))
+	assert.False(t, (&DependencyFilter{Is: true}).Matches(blocking))
+	assert.True(t, (&DependencyFilter{Is: false}).Matches(blocking))
+	assert.True(t, (&DependencyFilter{Blocking: true, Is: true}).Matches(blocking))
+	assert.False(t, (&DependencyFilter{Blocking: true, Is: true}).Matches(blocked))
+	assert.True(t, (&DependencyFilter{Blocking: true, Is: false}).Matches(blocked))
+}
+
+func TestUrgencyFilter(t *testing.T) {
+	task := &Task{Urgency: 10.75}
+
+	assert.True(t, (&UrgencyFilter{Threshold: 10, Above: true}).Matches(task))
+	assert.False(t, (&UrgencyFilter{Threshold: 10.75, Above: true}).Matches(task))
+	assert.False(t, (&UrgencyFilter{Threshold: 10, Above: false}).Matches(task))
+	assert.True(t, (&UrgencyFilter{Threshold: 11, Above: false}).Matches(task))
+}
+
 //nolint:funlen // comprehensive test cases
 func TestDueDateFilter(t *testing.T) {
 	tests := []struct {
@@ -369,6 +576,48 @@ func TestDueDateFilter(t *testing.T) {
 			task:   &Task{DueDate: "2024-01-14"},
 			want:   false,
 		},
+		{
+			name:   "due on range matches date inside range",
+			filter: &DueDateFilter{Op: DueOpOn, Date: "2024-01-01", End: "2024-01-31"},
+			task:   &Task{DueDate: "2024-01-15"},
+			want:   true,
+		},
+		{
+			name:   "due on range does not match date after range",
+			filter: &DueDateFilter{Op: DueOpOn, Date: "2024-01-01", End: "2024-01-31"},
+			task:   &Task{DueDate: "2024-02-01"},
+			want:   false,
+		},
+		{
+			name:   "due before excludes same date",
+			filter: &DueDateFilter{Op: DueOpBefore, Date: "2024-01-15"},
+			task:   &Task{DueDate: "2024-01-15"},
+			want:   false,
+		},
+		{
+			name:   "due before matches earlier date",
+			filter: &DueDateFilter{Op: DueOpBefore, Date: "2024-01-15"},
+			task:   &Task{DueDate: "2024-01-14"},
+			want:   true,
+		},
+		{
+			name:   "due after range compares against end of range",
+			filter: &DueDateFilter{Op: DueOpAfter, Date: "2024-01-01", End: "2024-01-31"},
+			task:   &Task{DueDate: "2024-01-15"},
+			want:   false,
+		},
+		{
+			name:   "due after matches later date",
+			filter: &DueDateFilter{Op: DueOpAfter, Date: "2024-01-15"},
+			task:   &Task{DueDate: "2024-01-16"},
+			want:   true,
+		},
+		{
+			name:   "due before does not match task without due date",
+			filter: &DueDateFilter{Op: DueOpBefore, Date: "2024-01-15"},
+			task:   &Task{DueDate: ""},
+			want:   false,
+		},
 		{
 			name:   "no due date matches task without due date",
 			filter: &DueDateFilter{Op: DueOpNone},
@@ -423,6 +672,48 @@ func TestTagFilter(t *testing.T) {
 			task:   &Task{Tags: []string{"urgent"}},
 			want:   false,
 		},
+		{
+			name:   "tag include ignores case",
+			filter: &TagFilter{Include: true, Tag: "Shopping"},
+			task:   &Task{Tags: []string{"shopping"}},
+			want:   true,
+		},
+		{
+			name:   "tag do not include ignores case",
+			filter: &TagFilter{Include: false, Tag: "ÉTÉ"},
+			task:   &Task{Tags: []string{"été"}},
+			want:   false,
+		},
+		{
+			name:   "tag include matches nested tags",
+			filter: &TagFilter{Include: true, Tag: "work"},
+			task:   &Task{Tags: []string{"work/clientA/site"}},
+			want:   true,
+		},
+		{
+			name:   "tag include matches nested tag prefix",
+			filter: &TagFilter{Include: true, Tag: "work/clientA"},
+			task:   &Task{Tags: []string{"work/clientA/site"}},
+			want:   true,
+		},
+		{
+			name:   "tag include does not match tags sharing a prefix",
+			filter: &TagFilter{Include: true, Tag: "work"},
+			task:   &Task{Tags: []string{"workout", "home/work"}},
+			want:   false,
+		},
+		{
+			name:   "exact tag include does not match nested tags",
+			filter: &TagFilter{Include: true, Tag: "work", Exact: true},
+			task:   &Task{Tags: []string{"work/clientA"}},
+			want:   false,
+		},
+		{
+			name:   "exact tag do not include matches nested tags",
+			filter: &TagFilter{Include: false, Tag: "Work", Exact: true},
+			task:   &Task{Tags: []string{"work/clientA"}},
+			want:   true,
+		},
 		{
 			name:   "tag do not include matches task without tag",
 			filter: &TagFilter{Include: false, Tag: "shopping"},
@@ -488,6 +779,12 @@ func TestPathFilter(t *testing.T) {
 			task:   &Task{FilePath: "other/todo.md"},
 			want:   false,
 		},
+		{
+			name:   "path includes ignores case",
+			filter: &PathFilter{Include: true, Substring: "Notes/Ideas"},
+			task:   &Task{FilePath: "notes/ideas.md"},
+			want:   true,
+		},
 		{
 			name:   "path does not include matches task not in path",
 			filter: &PathFilter{Include: false, Substring: "notes"},
@@ -530,9 +827,27 @@ func TestDescriptionFilter(t *testing.T) {
 			want:   false,
 		},
 		{
-			name:   "description includes is case sensitive",
+			name:   "description includes ignores case",
 			filter: &DescriptionFilter{Include: true, Substring: "Groceries"},
 			task:   &Task{Description: "buy groceries"},
+			want:   true,
+		},
+		{
+			name:   "description includes folds non-ASCII case",
+			filter: &DescriptionFilter{Include: true, Substring: "ÄRGER"},
+			task:   &Task{Description: "Kein Ärger mehr"},
+			want:   true,
+		},
+		{
+			name:   "description includes folds final sigma",
+			filter: &DescriptionFilter{Include: true, Substring: "ΣΊΣΥΦΟΣ"},
+			task:   &Task{Description: "Διάβασε τον μύθο του Σίσυφος"},
+			want:   true,
+		},
+		{
+			name:   "description does not include ignores case",
+			filter: &DescriptionFilter{Include: false, Substring: "MILK"},
+			task:   &Task{Description: "Buy milk"},
 			want:   false,
 		},
 		{
@@ -557,6 +872,70 @@ func TestDescriptionFilter(t *testing.T) {
 	}
 }
 
+func TestRegexFilter(t *testing.T) {
+	task := &Task{
+		Description: "Buy Milk",
+		FilePath:    "Journal/2026-10-16.md",
+		Heading:     "Errands",
+		Tags:        []string{"shopping", "home/kitchen"},
+	}
+
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{line: "description regex matches /^buy/", want: false},
+		{line: "description regex matches /^buy/i", want: true},
+		{line: "description regex does not match /milk$/i", want: false},
+		{line: "path regex matches /^Journal\\/\\d{4}-/", want: true},
+		{line: "path regex does not match /Projects/", want: true},
+		{line: "tags regex matches /^#home\\//", want: true},
+		{line: "tag regex matches /^shopping/", want: false},
+		{line: "tags regex does not match /#work/", want: true},
+		{line: "heading regex matches /errand/i", want: true},
+		{line: "heading regex matches /^$/", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			query, err := ParseQuery(tt.line)
+			require.NoError(t, err)
+			require.Len(t, query.Filters, 1)
+			assert.Equal(t, tt.want, query.Filters[0].Matches(task))
+		})
+	}
+}
+
+func TestParseQueryInvalidRegex(t *testing.T) {
+	tests := []struct {
+		line    string
+		message string
+	}{
+		{line: "description regex matches milk", message: "regex must be enclosed in slashes, like /milk/"},
+		{line: "description regex matches /(milk/", message: "invalid regex /(milk/"},
+		{line: "path regex matches /notes/g", message: `unsupported regex flag 'g' in /notes/g`},
+		{line: "heading regex matches //", message: "empty regex //"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			_, err := ParseQuery(tt.line)
+
+			var qerr *QueryError
+			require.ErrorAs(t, err, &qerr)
+			require.Len(t, qerr.Errors, 1)
+			assert.Contains(t, qerr.Errors[0].Message, tt.message)
+		})
+	}
+}
+
+func TestFoldCase(t *testing.T) {
+	assert.Equal(t, foldCase("Kelvin"), foldCase("\u212Aelvin"))
+	assert.Equal(t, foldCase("σοφός"), foldCase("ΣΟΦΌΣ"))
+	assert.True(t, containsFold("Ünïcödé Text", "ÏCÖD"))
+	assert.False(t, containsFold("straße", "STRASSE"), "full case folding is not applied")
+}
+
 func TestQueryMatches(t *testing.T) {
 	query := &Query{
 		Filters: []Filter{
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryLineError describes a single query line that could not be parsed
+type QueryLineError struct {
+	Text       string `json:"text"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+	Line       int    `json:"line"`
+}
+
+// QueryError is returned by ParseQuery when one or more lines are unknown or
+// malformed
+type QueryError struct {
+	Errors []QueryLineError
+}
+
+func (e *QueryError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+
+	for _, le := range e.Errors {
+		msg := fmt.Sprintf("line %d: %q: %s", le.Line, le.Text, le.Message)
+		if le.Suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", le.Suggestion)
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// knownInstructions lists the leading words of every supported query
+// instruction, used to suggest corrections for unknown lines
+func knownInstructions() []string {
+	return []string{
+		"done",
+		"not done",
+		"status.type is",
+		"status.type is not",
+		"status.name includes",
+		"status.name does not include",
+		"due",
+		"due on",
+		"due in",
+		"due before",
+		"due after",
+		"due on or before",
+		"due on or after",
+		"no due date",
+		"has due date",
+		"is recurring",
+		"is not recurring",
+		"is blocked",
+		"is not blocked",
+		"is blocking",
+		"is not blocking",
+		"exclude sub-items",
+		"has children",
+		"no children",
+		"tag include",
+		"tags include",
+		"tag do not include",
+		"tags do not include",
+		"tags include exactly",
+		"tags do not include exactly",
+		"has tags",
+		"no tags",
+		"path includes",
+		"path does not include",
+		"description includes",
+		"description does not include",
+		"description regex matches",
+		"description regex does not match",
+		"path regex matches",
+		"path regex does not match",
+		"tags regex matches",
+		"tags regex does not match",
+		"heading regex matches",
+		"heading regex does not match",
+		"sort by priority",
+		"sort by due",
+		"sort by urgency",
+		"sort by path",
+		"sort by filename",
+		"sort by description",
+		"sort by status",
+		"sort by tag",
+		"sort by scheduled",
+		"sort by start",
+		"sort by done",
+		"sort by created",
+		"sort by cancelled",
+		"sort by happens",
+		"sort by heading",
+		"urgency above",
+		"urgency below",
+		"group by folder",
+		"group by filename",
+		"group by heading",
+		"group by tags",
+		"group by due",
+		"group by priority",
+		"group by status",
+		"group by happens",
+		"limit groups to",
+		"limit",
+		"offset",
+	}
+}
+
+// suggestInstruction returns the known instruction closest to the start of
+// line, or "" if none is reasonably close
+func suggestInstruction(line string) string {
+	words := strings.Fields(strings.ToLower(line))
+
+	best := ""
+	bestDist := -1
+
+	for _, candidate := range knownInstructions() {
+		n := len(strings.Fields(candidate))
+		if n > len(words) {
+			continue
+		}
+
+		dist := levenshtein(strings.Join(words[:n], " "), candidate)
+		if bestDist < 0 || dist < bestDist || (dist == bestDist && len(candidate) > len(best)) {
+			best, bestDist = candidate, dist
+		}
+	}
+
+	// allow roughly one typo per three characters
+	if bestDist < 0 || bestDist > max(2, len(best)/3) {
+		return ""
+	}
+
+	return best
+}
+
+// levenshtein returns the edit distance between a and b, in runes
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQueryUnknownLines(t *testing.T) {
+	_, err := ParseQuery("not done\ntag includes #work\n\nsort by priority\nbogus\nlimit 999999999999999999999")
+	require.Error(t, err)
+
+	var qerr *QueryError
+	require.ErrorAs(t, err, &qerr)
+	require.Len(t, qerr.Errors, 3)
+
+	assert.Equal(t, 2, qerr.Errors[0].Line)
+	assert.Equal(t, "tag includes #work", qerr.Errors[0].Text)
+	assert.Equal(t, "unknown instruction", qerr.Errors[0].Message)
+	assert.Equal(t, "tag include", qerr.Errors[0].Suggestion)
+
+	assert.Equal(t, 5, qerr.Errors[1].Line)
+	assert.Empty(t, qerr.Errors[1].Suggestion)
+
+	assert.Equal(t, 6, qerr.Errors[2].Line)
+	assert.Contains(t, qerr.Errors[2].Message, "invalid limit value")
+
+	assert.Contains(t, err.Error(), `line 2: "tag includes #work": unknown instruction (did you mean "tag include"?)`)
+}
+
+func TestParseQueryLenient(t *testing.T) {
+	q, err := ParseQueryWithOptions("not done\ntag includes #work", QueryOptions{Lenient: true})
+	require.NoError(t, err)
+	assert.Len(t, q.Filters, 1)
+
+	// malformed lines are still errors in lenient mode
+	_, err = ParseQueryWithOptions("due before someday", QueryOptions{Lenient: true})
+	require.Error(t, err)
+}
+
+func TestSuggestInstruction(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "tag includes #work", want: "tag include"},
+		{line: "descripton includes milk", want: "description includes"},
+		{line: "sort by prority", want: "sort by priority"},
+		{line: "not dne", want: "not done"},
+		{line: "path include notes", want: "path includes"},
+		{line: "completely unrelated text", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			assert.Equal(t, tt.want, suggestInstruction(tt.line))
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("done", "done"))
+	assert.Equal(t, 1, levenshtein("done", "dne"))
+	assert.Equal(t, 3, levenshtein("kitten", "sitting"))
+	assert.Equal(t, 1, levenshtein("café", "cafe"))
+	assert.Equal(t, 4, levenshtein("", "done"))
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type recurrenceUnit int
+
+const (
+	recurDay recurrenceUnit = iota
+	recurWeek
+	recurMonth
+	recurYear
+)
+
+// recurrenceRule is a parsed Tasks recurrence rule such as "every 2 weeks on
+// Monday, Friday" or "every month on the last Friday when done"
+type recurrenceRule struct {
+	// weekdays restricts weekly rules to the given days
+	weekdays []time.Weekday
+	interval int
+	unit     recurrenceUnit
+	// monthDay pins monthly rules to a day of the month; -1 means the last day
+	monthDay int
+	// nth and nthWeekday pin monthly rules to e.g. the 2nd Tuesday (nth 2)
+	// or the last Friday (nth -1)
+	nth        int
+	nthWeekday time.Weekday
+	whenDone   bool
+}
+
+var (
+	recurEveryRegex       = regexp.MustCompile(`^every(?: (\d+))? (day|week|month|year)s?(?: on (.+))?$`)
+	recurWeekdaysRegex    = regexp.MustCompile(`^every ((?:monday|tuesday|wednesday|thursday|friday|saturday|sunday)(?:(?:, ?| and )(?:monday|tuesday|wednesday|thursday|friday|saturday|sunday))*)$`)
+	recurMonthDayRegex    = regexp.MustCompile(`^the (\d{1,2})(?:st|nd|rd|th)$`)
+	recurMonthNthDayRegex = regexp.MustCompile(`^the (1st|2nd|3rd|4th|5th|last) (monday|tuesday|wednesday|thursday|friday|saturday|sunday)$`)
+)
+
+// parseRecurrence parses the text following a 🔁 signifier
+//
+//nolint:gocyclo // one branch per supported rule form
+func parseRecurrence(text string) (*recurrenceRule, error) {
+	rule := &recurrenceRule{interval: 1}
+
+	s := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	if trimmed, ok := strings.CutSuffix(s, " when done"); ok {
+		rule.whenDone = true
+		s = trimmed
+	}
+
+	if s == "every weekday" {
+		rule.unit = recurWeek
+		rule.weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+		return rule, nil
+	}
+
+	if matches := recurWeekdaysRegex.FindStringSubmatch(s); matches != nil {
+		rule.unit = recurWeek
+		rule.weekdays = parseWeekdayList(matches[1])
+
+		return rule, nil
+	}
+
+	matches := recurEveryRegex.FindStringSubmatch(s)
+	if matches == nil {
+		return nil, fmt.Errorf("unsupported recurrence rule %q", text)
+	}
+
+	if matches[1] != "" {
+		n, err := strconv.Atoi(matches[1])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid interval in recurrence rule %q", text)
+		}
+
+		rule.interval = n
+	}
+
+	switch matches[2] {
+	case "day":
+		rule.unit = recurDay
+	case "week":
+		rule.unit = recurWeek
+	case "month":
+		rule.unit = recurMonth
+	default:
+		rule.unit = recurYear
+	}
+
+	on := matches[3]
+	if on == "" {
+		return rule, nil
+	}
+
+	switch rule.unit {
+	case recurWeek:
+		rule.weekdays = parseWeekdayList(on)
+		if len(rule.weekdays) > 0 {
+			return rule, nil
+		}
+	case recurMonth:
+		if on == "the last" || on == "the last day" {
+			rule.monthDay = -1
+
+			return rule, nil
+		}
+
+		if m := recurMonthDayRegex.FindStringSubmatch(on); m != nil {
+			day, _ := strconv.Atoi(m[1])
+			if day >= 1 && day <= 31 {
+				rule.monthDay = day
+
+				return rule, nil
+			}
+		}
+
+		if m := recurMonthNthDayRegex.FindStringSubmatch(on); m != nil {
+			rule.nth = -1
+			if m[1] != "last" {
+				rule.nth, _ = strconv.Atoi(m[1][:1])
+			}
+
+			rule.nthWeekday = parseWeekday(m[2])
+
+			return rule, nil
+		}
+	case recurDay, recurYear:
+	}
+
+	return nil, fmt.Errorf("unsupported recurrence rule %q", text)
+}
+
+// parseWeekdayList parses "monday, friday" or "monday and friday"
+func parseWeekdayList(s string) []time.Weekday {
+	var days []time.Weekday
+
+	for _, name := range strings.FieldsFunc(strings.ReplaceAll(s, " and ", ","), func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		if !relativeDayRegex.MatchString(name) {
+			return nil
+		}
+
+		day := parseWeekday(name)
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+
+	return days
+}
+
+// next returns the first occurrence strictly after ref, or an error if the
+// rule never matches a day (e.g. "every 12 months on the 30th" counted from
+// February)
+func (r *recurrenceRule) next(ref time.Time) (time.Time, error) {
+	switch r.unit {
+	case recurDay:
+		return ref.AddDate(0, 0, r.interval), nil
+	case recurWeek:
+		if len(r.weekdays) == 0 {
+			return ref.AddDate(0, 0, 7*r.interval), nil
+		}
+
+		// later in the same week?
+		for d := ref.AddDate(0, 0, 1); !d.Equal(weekStart(ref).AddDate(0, 0, 7)); d = d.AddDate(0, 0, 1) {
+			if slices.Contains(r.weekdays, d.Weekday()) {
+				return d, nil
+			}
+		}
+
+		// otherwise the first matching day of the next week in the cycle
+		d := weekStart(ref).AddDate(0, 0, 7*r.interval)
+		for !slices.Contains(r.weekdays, d.Weekday()) {
+			d = d.AddDate(0, 0, 1)
+		}
+
+		return d, nil
+	case recurMonth:
+		return r.nextMonthly(ref)
+	default:
+		return addMonthsClamped(ref, 12*r.interval, ref.Day()), nil
+	}
+}
+
+func (r *recurrenceRule) nextMonthly(ref time.Time) (time.Time, error) {
+	if r.monthDay == 0 && r.nth == 0 {
+		return addMonthsClamped(ref, r.interval, ref.Day()), nil
+	}
+
+	// try the reference month first, then step by the interval. Some rules
+	// only match a few of the months they visit (a fifth Friday in February
+	// comes every 28 years or so), and others none at all, so give up after
+	// a bounded number of steps.
+	monthStart := time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for step := range 12 * r.interval {
+		candidate, ok := r.dayInMonth(monthStart.AddDate(0, step*r.interval, 0))
+		if ok && candidate.After(ref) {
+			return candidate, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("no month matches the rule when counting from %s", ref.Format(dateLayout))
+}
+
+// dayInMonth returns the day this rule selects in the month starting at
+// monthStart, or false if the month has no such day (e.g. the 31st)
+func (r *recurrenceRule) dayInMonth(monthStart time.Time) (time.Time, bool) {
+	last := monthStart.AddDate(0, 1, -1)
+
+	switch {
+	case r.monthDay == -1:
+		return last, true
+	case r.monthDay > 0:
+		if r.monthDay > last.Day() {
+			return time.Time{}, false
+		}
+
+		return monthStart.AddDate(0, 0, r.monthDay-1), true
+	case r.nth == -1:
+		d := last
+		for d.Weekday() != r.nthWeekday {
+			d = d.AddDate(0, 0, -1)
+		}
+
+		return d, true
+	default:
+		d := monthStart
+		for d.Weekday() != r.nthWeekday {
+			d = d.AddDate(0, 0, 1)
+		}
+
+		d = d.AddDate(0, 0, 7*(r.nth-1))
+		if d.Month() != monthStart.Month() {
+			return time.Time{}, false
+		}
+
+		return d, true
+	}
+}
+
+// addMonthsClamped adds months to t, clamping day to the end of the target
+// month as the Tasks plugin does (so Jan 31 + 1 month is Feb 28)
+func addMonthsClamped(t time.Time, months, day int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, months, 0)
+	last := first.AddDate(0, 1, -1).Day()
+
+	return first.AddDate(0, 0, min(day, last)-1)
+}
+
+// nextOccurrenceLine builds the line for the next occurrence of the
+// recurring task on line, completed on doneDay. Dates are moved so that the
+// reference date (due, else scheduled, else start) lands on the next
+// occurrence, and the others keep their distance from it. With "when done"
+// the next occurrence is computed from doneDay instead of the reference date.
+func nextOccurrenceLine(line string, doneDay time.Time) (string, error) {
+	task := ParseTask(line, "", 0)
+	if task == nil || task.Recurrence == "" {
+		return "", errors.New("task is not recurring")
+	}
+
+	rule, err := parseRecurrence(task.Recurrence)
+	if err != nil {
+		return "", err
+	}
+
+	l, _ := splitTaskLine(line)
+
+	l.symbol = " "
+	l.setDate(doneDateRegex, "✅", "")
+	l.setDate(cancelledDateRegex, "❌", "")
+
+	trimmed := strings.TrimRight(l.content, " \t")
+	if loc := blockLinkRegex.FindStringIndex(trimmed); loc != nil {
+		l.content = trimmed[:loc[0]]
+	}
+
+	refDate := cmp.Or(task.DueDate, task.ScheduledDate, task.StartDate)
+	if refDate == "" {
+		return l.String(), nil
+	}
+
+	ref, err := time.Parse(dateLayout, refDate)
+	if err != nil {
+		return "", err
+	}
+
+	base := ref
+	if rule.whenDone {
+		base = doneDay
+	}
+
+	nextRef, err := rule.next(base)
+	if err != nil {
+		return "", err
+	}
+
+	for _, field := range []struct {
+		re    *regexp.Regexp
+		emoji string
+		date  string
+	}{
+		{startDateRegex, "🛫", task.StartDate},
+		{scheduledDateRegex, "⏳", task.ScheduledDate},
+		{dueDateRegex, "📅", task.DueDate},
+	} {
+		d, err := time.Parse(dateLayout, field.date)
+		if err != nil {
+			continue
+		}
+
+		offset := int(d.Sub(ref).Hours() / 24)
+		l.setDate(field.re, field.emoji, nextRef.AddDate(0, 0, offset).Format(dateLayout))
+	}
+
+	return l.String(), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	tests := []struct {
+		rule string
+		from string
+		want string
+	}{
+		{rule: "every day", from: "2026-10-16", want: "2026-10-17"},
+		{rule: "every 3 days", from: "2026-10-16", want: "2026-10-19"},
+		{rule: "every week", from: "2026-10-16", want: "2026-10-23"},
+		{rule: "every 2 weeks", from: "2026-10-16", want: "2026-10-30"},
+		{rule: "every week on Monday, Friday", from: "2026-10-16", want: "2026-10-19"},
+		{rule: "every Monday and Wednesday", from: "2026-10-19", want: "2026-10-21"},
+		{rule: "every 2 weeks on Monday", from: "2026-10-16", want: "2026-10-26"},
+		{rule: "every weekday", from: "2026-10-16", want: "2026-10-19"},
+		{rule: "every month", from: "2026-01-31", want: "2026-02-28"},
+		{rule: "every month on the 31st", from: "2026-01-31", want: "2026-03-31"},
+		{rule: "every month on the 15th", from: "2026-10-16", want: "2026-11-15"},
+		{rule: "every month on the last", from: "2026-10-16", want: "2026-10-31"},
+		{rule: "every month on the 2nd Tuesday", from: "2026-10-16", want: "2026-11-10"},
+		{rule: "every month on the last Friday", from: "2026-10-16", want: "2026-10-30"},
+		{rule: "every 3 months on the 1st", from: "2026-10-16", want: "2027-01-01"},
+		{rule: "every year", from: "2024-02-29", want: "2025-02-28"},
+		{rule: "every week when done", from: "2026-10-16", want: "2026-10-23"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := parseRecurrence(tt.rule)
+			require.NoError(t, err)
+
+			from, err := time.Parse(dateLayout, tt.from)
+			require.NoError(t, err)
+
+			next, err := rule.next(from)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, next.Format(dateLayout))
+		})
+	}
+}
+
+func TestRecurrenceNextNoMatch(t *testing.T) {
+	// every 12 months from February only ever visits February
+	rule, err := parseRecurrence("every 12 months on the 30th")
+	require.NoError(t, err)
+
+	_, err = rule.next(time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC))
+	require.Error(t, err)
+
+	// rare, but not impossible
+	rule, err = parseRecurrence("every 12 months on the 5th Sunday")
+	require.NoError(t, err)
+
+	next, err := rule.next(time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "2032-02-29", next.Format(dateLayout))
+}
+
+func TestParseRecurrenceErrors(t *testing.T) {
+	for _, rule := range []string{
+		"every fortnight",
+		"every 0 days",
+		"every month on the 32nd",
+		"every day on Monday",
+		"every week on Funday",
+	} {
+		t.Run(rule, func(t *testing.T) {
+			_, err := parseRecurrence(rule)
+			assert.Error(t, err)
+		})
+	}
+
+	rule, err := parseRecurrence("Every Week When Done")
+	require.NoError(t, err)
+	assert.True(t, rule.whenDone)
+}
+
+func TestNextOccurrenceLine(t *testing.T) {
+	// Friday
+	day := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "due date is the reference",
+			line: "- [x] Water plants 🔁 every week ⏳ 2026-10-12 📅 2026-10-14 ✅ 2026-10-16",
+			want: "- [ ] Water plants 🔁 every week ⏳ 2026-10-19 📅 2026-10-21",
+		},
+		{
+			name: "scheduled date is the reference without a due date",
+			line: "  - [x] Review 🔁 every month 🛫 2026-09-28 ⏳ 2026-09-30 ✅ 2026-10-16 ^abc",
+			want: "  - [ ] Review 🔁 every month 🛫 2026-10-28 ⏳ 2026-10-30",
+		},
+		{
+			name: "when done counts from the done date",
+			line: "- [x] Haircut 🔁 every 4 weeks when done 📅 2026-09-01 ✅ 2026-10-16",
+			want: "- [ ] Haircut 🔁 every 4 weeks when done 📅 2026-11-13",
+		},
+		{
+			name: "no dates",
+			line: "- [x] Stretch 🔁 every day ✅ 2026-10-16",
+			want: "- [ ] Stretch 🔁 every day",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextOccurrenceLine(tt.line, day)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := nextOccurrenceLine("- [x] Once ✅ 2026-10-16", day)
+	require.Error(t, err)
+
+	_, err = nextOccurrenceLine("- [x] Odd 🔁 every blue moon ✅ 2026-10-16", day)
+	require.Error(t, err)
+}
@@ -3,13 +3,24 @@ package main
 import (
 	"bufio"
 	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
+// maxLineLength is the length of the longest line parsed for tasks, in bytes.
+// Longer lines are skipped with a warning.
+const maxLineLength = 64 * 1024
+
 // ScanTasks scans markdown files in the given root directories and returns all tasks
 func ScanTasks(roots []string) ([]*Task, error) {
 	tasks, _, err := ScanTasksWithQuery(roots, nil)
@@ -18,99 +29,275 @@ func ScanTasks(roots []string) ([]*Task, error) {
 }
 
 // ScanTasksWithQuery scans markdown files and filters tasks using the provided query
-//
-//nolint:gocyclo // complexity from walking directories and filtering
 func ScanTasksWithQuery(roots []string, query *Query) ([]*Task, int, error) {
-	allTasks := make([]*Task, 0)
+	result, err := ScanVault(roots, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result.Tasks, result.Total, nil
+}
+
+// ScanResult holds the tasks matching a query along with facts about the
+// whole vault discovered while scanning it
+type ScanResult struct {
+	Tasks []*Task
+	// Cycles lists the dependency cycles in the vault, each as the ids of the
+	// tasks in it
+	Cycles [][]string
+	// Warnings lists the files and folders that couldn't be fully read
+	Warnings []ScanWarning
+	// Total is the number of matching tasks before pagination
+	Total int
+}
+
+// ScanOptions configures which files a scan reads
+type ScanOptions struct {
+	// Exclude lists globs of files and folders to skip, matched against
+	// paths relative to the scanned root (e.g. "Archive/*" or "*.excalidraw.md").
+	// A glob without a slash matches names at any depth.
+	Exclude []string
+	// Index, if set and ready, provides the tasks instead of reading the
+	// files
+	Index *TaskIndex
+	// Workers is the number of files parsed concurrently. Zero (or less)
+	// means one per CPU.
+	Workers int
+}
+
+// ScanVault scans markdown files in the given root directories and returns
+// the tasks matching query
+func ScanVault(roots []string, query *Query) (*ScanResult, error) {
+	return ScanVaultWithOptions(context.Background(), roots, query, ScanOptions{})
+}
+
+// ScanVaultWithOptions scans markdown files in the given root directories and
+// returns the tasks matching query. Hidden folders (such as .obsidian, .trash
+// and .git), node_modules folders, the vault's excluded files and
+// opts.Exclude are skipped. Dependencies are resolved across every task in
+// the roots before filtering, so that filters such as "is blocked" see the
+// whole vault. The scan stops early, returning ctx's error, once ctx is
+// done.
+func ScanVaultWithOptions(ctx context.Context, roots []string, query *Query, opts ScanOptions) (*ScanResult, error) {
+	allTasks, warnings, indexed, err := opts.Index.collect(roots, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	if !indexed {
+		allTasks, warnings, err = collectTasks(ctx, roots, opts.Exclude, opts.Workers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// files are parsed concurrently (and the index is unordered), so put the
+	// tasks in file and line order before anything depends on their order
+	sortTasks(allTasks, nil)
+	sortWarnings(warnings)
+
+	cycles := linkDependencies(allTasks)
+
+	day := today(time.Now, time.Local)
+	if query != nil && !query.Today.IsZero() {
+		day = query.Today
+	}
+
+	for _, task := range allTasks {
+		task.Urgency = urgency(task, day)
+	}
+
+	matched := allTasks
+	if query != nil {
+		matched = make([]*Task, 0, len(allTasks))
+
+		for _, task := range allTasks {
+			if query.Matches(task) {
+				matched = append(matched, task)
+			}
+		}
+	}
+
+	sortTasks(matched, query)
+
+	return &ScanResult{
+		Tasks:    applyPagination(matched, query),
+		Cycles:   cycles,
+		Warnings: warnings,
+		Total:    len(matched),
+	}, nil
+}
+
+// collectTasks parses every task in the markdown files under roots, skipping
+// excluded files and folders, with up to workers files parsed at a time. Files
+// and folders that can't be read are reported as warnings.
+func collectTasks(ctx context.Context, roots, exclude []string, workers int) ([]*Task, []ScanWarning, error) {
+	var (
+		allTasks = make([]*Task, 0)
+		warnings []ScanWarning
+		mu       sync.Mutex
+	)
 
 	for _, root := range roots {
 		absRoot, err := filepath.Abs(root)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to get absolute path for %q: %w", root, err)
+			return nil, nil, fmt.Errorf("failed to get absolute path for %q: %w", root, err)
 		}
 
-		err = filepath.Walk(absRoot, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+		settings, err := LoadVaultSettings(absRoot)
+		if err != nil {
+			return nil, nil, err
+		}
 
-			// Only process markdown files
-			if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".md") {
-				return nil
-			}
+		rules, err := newExcludeRules(absRoot, exclude)
+		if err != nil {
+			return nil, nil, err
+		}
 
-			tasks, parseErr := parseTasksFromFile(path, absRoot)
+		dirWarnings, err := walkMarkdownFiles(ctx, absRoot, rules, workers, func(path string, _ os.FileInfo) {
+			tasks, fileWarnings, parseErr := parseTasksFromFile(path, absRoot, settings)
 			if parseErr != nil {
-				// Log error but continue scanning other files
-				// We could log parseErr here if we had a logger
-				return nil //nolint:nilerr // intentionally continue on parse errors
+				// skip the file, but say so
+				fileWarnings = append(fileWarnings, newScanWarning(absRoot, path, WarningUnreadableFile, parseErr))
 			}
 
-			// Filter tasks if query is provided
-			if query != nil {
-				for _, task := range tasks {
-					if query.Matches(task) {
-						allTasks = append(allTasks, task)
-					}
+			mu.Lock()
+			allTasks = append(allTasks, tasks...)
+			warnings = append(warnings, fileWarnings...)
+			mu.Unlock()
+		})
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to walk directory %q: %w", root, err)
+		}
+
+		warnings = append(warnings, dirWarnings...)
+	}
+
+	return allTasks, warnings, nil
+}
+
+// walkMarkdownFiles calls fn for every markdown file under dir, which is the
+// rules' root or a folder inside it, skipping the files and folders rules
+// exclude. fn is called from up to workers goroutines at once (one per CPU if
+// workers is not positive). Once ctx is done, the walk stops and no more
+// files are handed to fn. Folders that can't be read are skipped and
+// returned as warnings; only an unreadable dir is an error. A symlinked dir is
+// followed, and fn is given paths under dir as spelled.
+func walkMarkdownFiles(
+	ctx context.Context, dir string, rules *excludeRules, workers int, fn func(path string, info os.FileInfo),
+) ([]ScanWarning, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type markdownFile struct {
+		info os.FileInfo
+		path string
+	}
+
+	files := make(chan markdownFile)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Go(func() {
+			for file := range files {
+				if ctx.Err() == nil {
+					fn(file.path, file.info)
 				}
-			} else {
-				allTasks = append(allTasks, tasks...)
+			}
+		})
+	}
+
+	var warnings []ScanWarning
+
+	walkDir, unresolve := followDir(dir)
+
+	err := filepath.Walk(walkDir, func(path string, info os.FileInfo, err error) error {
+		isDir := path == walkDir
+		path = unresolve(path)
+
+		switch {
+		case err == nil:
+		case isDir && info == nil:
+			return err
+		case info != nil && info.IsDir():
+			warnings = append(warnings, newScanWarning(rules.root, path, WarningUnreadableDirectory, err))
+
+			return filepath.SkipDir
+		case errors.Is(err, fs.ErrNotExist):
+			// deleted since its folder was listed
+			return nil
+		default:
+			warnings = append(warnings, newScanWarning(rules.root, path, WarningUnreadableFile, err))
+
+			return nil
+		}
+
+		if rules.skip(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
 
 			return nil
-		})
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to walk directory %q: %w", root, err)
 		}
-	}
 
-	sortTasks(allTasks, query)
+		// Only process markdown files
+		if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".md") {
+			return nil
+		}
+
+		select {
+		case files <- markdownFile{path: path, info: info}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
 
-	total := len(allTasks)
-	allTasks = applyPagination(allTasks, query)
+	close(files)
+	wg.Wait()
 
-	return allTasks, total, nil
+	return warnings, err
+}
+
+// followDir returns the folder to walk for dir, with symlinks resolved, since
+// filepath.Walk and filepath.WalkDir don't follow a symlinked root. The
+// returned function maps paths under that folder back under dir.
+func followDir(dir string) (string, func(path string) string) {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil || resolved == dir {
+		return dir, func(path string) string { return path }
+	}
+
+	return resolved, func(path string) string {
+		rel, err := filepath.Rel(resolved, path)
+		if err != nil {
+			return path
+		}
+
+		return filepath.Join(dir, rel)
+	}
 }
 
-//nolint:gocognit,gocyclo // multi-key sort with special "none/empty sorts last" logic
 func sortTasks(tasks []*Task, query *Query) {
 	slices.SortStableFunc(tasks, func(a, b *Task) int {
 		if query != nil {
 			for _, key := range query.SortBy {
-				var c int
-
-				switch key.Field {
-				case SortByPriority:
-					switch {
-					case a.Priority == PriorityNone && b.Priority == PriorityNone:
-						c = 0
-					case a.Priority == PriorityNone:
-						return 1
-					case b.Priority == PriorityNone:
-						return -1
-					default:
-						c = cmp.Compare(a.Priority, b.Priority)
-					}
-				case SortByDue:
-					switch {
-					case a.DueDate == "" && b.DueDate == "":
-						c = 0
-					case a.DueDate == "":
-						return 1
-					case b.DueDate == "":
-						return -1
-					default:
-						c = cmp.Compare(a.DueDate, b.DueDate)
-					}
+				c, final := compareBySortKey(key, a, b)
+				if c == 0 {
+					continue
 				}
 
-				if c != 0 {
-					if key.Reverse {
-						return -c
-					}
-
-					return c
+				if key.Reverse && !final {
+					return -c
 				}
+
+				return c
 			}
 		}
 
@@ -142,24 +329,64 @@ func applyPagination(tasks []*Task, query *Query) []*Task {
 	return tasks
 }
 
-func parseTasksFromFile(filePath, rootDir string) ([]*Task, error) {
+// parseTasksFromFile returns the tasks in the markdown file at filePath,
+// along with warnings about the lines it had to skip. An error means the
+// file couldn't be read.
+func parseTasksFromFile(filePath, rootDir string, settings *VaultSettings) ([]*Task, []ScanWarning, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
-	var tasks []*Task
+	var (
+		tasks    []*Task
+		warnings []ScanWarning
+		lists    listTracker
+		md       markdownLines
+		heading  string
+	)
 
-	scanner := bufio.NewScanner(file)
+	reader := bufio.NewReaderSize(file, maxLineLength)
 	lineNumber := 0
 
-	for scanner.Scan() {
+	for {
+		line, tooLong, err := readLine(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading file %q: %w", filePath, err)
+		}
+
 		lineNumber++
-		line := scanner.Text()
 
-		task := ParseTask(line, filePath, lineNumber)
+		if tooLong {
+			// such lines are usually embedded data, not tasks; it still
+			// counts as a line of the note
+			md.next("")
+
+			warnings = append(warnings, newScanWarning(rootDir, filePath, WarningLineTooLong,
+				fmt.Errorf("line %d is longer than %d bytes and was skipped", lineNumber, maxLineLength)))
+
+			continue
+		}
+
+		if !md.next(line) {
+			continue
+		}
+
+		if m := headingRegex.FindStringSubmatch(line); m != nil {
+			heading = m[2]
+		}
+
+		task := ParseTaskWithSettings(line, filePath, lineNumber, settings)
+		lists.add(line, taskID(filePath, lineNumber), task)
+
 		if task != nil {
+			task.Heading = heading
+
 			// Make file path relative to root if possible
 			relPath, err := filepath.Rel(rootDir, filePath)
 			if err == nil {
@@ -170,9 +397,29 @@ func parseTasksFromFile(filePath, rootDir string) ([]*Task, error) {
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file %q: %w", filePath, err)
+	return tasks, warnings, nil
+}
+
+// readLine returns the next line from r, without its line ending. A line
+// that doesn't fit in r's buffer is discarded, and reported with tooLong. It
+// returns io.EOF once every line has been read.
+func readLine(r *bufio.Reader) (line string, tooLong bool, err error) {
+	data, err := r.ReadSlice('\n')
+	for errors.Is(err, bufio.ErrBufferFull) {
+		tooLong = true
+		data, err = r.ReadSlice('\n')
+	}
+
+	// the last line needn't end with a newline
+	if errors.Is(err, io.EOF) && (len(data) > 0 || tooLong) {
+		err = nil
 	}
 
-	return tasks, nil
+	if err != nil || tooLong {
+		return "", tooLong, err
+	}
+
+	line = strings.TrimSuffix(string(data), "\n")
+
+	return strings.TrimSuffix(line, "\r"), false, nil
 }
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -241,3 +248,216 @@ func TestScanTasksWithQuerySortMultiKey(t *testing.T) {
 	assert.Equal(t, "High mid", tasks[2].Description, "same priority, middle due")
 	assert.Equal(t, "High early", tasks[3].Description, "same priority, earliest due last")
 }
+
+func TestScanTasksWithQuerySortByUrgency(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(tmpDir, "tasks.md"), []byte(`- [ ] No dates
+- [ ] Overdue 📅 2026-10-01
+- [ ] Scheduled ⏳ 2026-10-16
+- [ ] Not started 🛫 2026-11-01
+`), 0o600)
+	require.NoError(t, err)
+
+	query, err := ParseQueryWithOptions("sort by urgency\nurgency above 0", QueryOptions{
+		Now: func() time.Time { return time.Date(2026, time.October, 16, 9, 0, 0, 0, time.UTC) },
+	})
+	require.NoError(t, err)
+
+	tasks, total, err := ScanTasksWithQuery([]string{tmpDir}, query)
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+	assert.Equal(t, 3, total)
+
+	assert.Equal(t, "Overdue", tasks[0].Description)
+	assert.InDelta(t, 13.95, tasks[0].Urgency, 0.001)
+	assert.Equal(t, "Scheduled", tasks[1].Description)
+	assert.InDelta(t, 6.95, tasks[1].Urgency, 0.001)
+	assert.Equal(t, "No dates", tasks[2].Description)
+}
+
+func TestScanVaultDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// dependencies are resolved across files, even ones the query filters out
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.md"), []byte(`- [ ] Build 🆔 build
+- [ ] Loop one 🆔 one ⛔ two
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "b.md"), []byte(`- [ ] Deploy ⛔ build
+- [ ] Loop two 🆔 two ⛔ one
+`), 0o600))
+
+	query, err := ParseQuery("is blocked\npath includes b.md")
+	require.NoError(t, err)
+
+	result, err := ScanVault([]string{tmpDir}, query)
+	require.NoError(t, err)
+	require.Len(t, result.Tasks, 2)
+	assert.Equal(t, "Deploy", result.Tasks[0].Description)
+	assert.Equal(t, "Loop two", result.Tasks[1].Description)
+
+	require.Len(t, result.Cycles, 1)
+	assert.Equal(t, []string{filepath.Join(tmpDir, "a.md") + ":2", filepath.Join(tmpDir, "b.md") + ":2"}, result.Cycles[0])
+}
+
+func TestScanTasksHeadings(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "todo.md"), []byte(`- [ ] Before any heading
+# Inbox
+- [ ] In inbox #tag
+## Work  
+- [ ] At work
+`), 0o600))
+
+	tasks, err := ScanTasks([]string{tmpDir})
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+	assert.Empty(t, tasks[0].Heading)
+	assert.Equal(t, "Inbox", tasks[1].Heading)
+	assert.Equal(t, "Work", tasks[2].Heading)
+}
+
+func TestScanVaultWorkers(t *testing.T) {
+	vault := t.TempDir()
+
+	for i := range 50 {
+		name := fmt.Sprintf("Notes/%02d/note-%02d.md", i%7, i)
+		if i%2 == 0 {
+			name = fmt.Sprintf("note-%02d.md", i)
+		}
+
+		writeNote(t, vault, name, fmt.Sprintf("- [ ] Task %d a\n- [ ] Task %d b\n", i, i))
+	}
+
+	var want []string
+
+	for _, workers := range []int{0, 1, 3, 16} {
+		result, err := ScanVaultWithOptions(t.Context(), []string{vault}, nil, ScanOptions{Workers: workers})
+		require.NoError(t, err)
+
+		var got []string
+		for _, task := range result.Tasks {
+			got = append(got, task.ID)
+		}
+
+		if want == nil {
+			want = got
+
+			assert.Len(t, want, 100)
+			assert.IsNonDecreasing(t, want)
+
+			continue
+		}
+
+		assert.Equal(t, want, got, "workers: %d", workers)
+	}
+}
+
+func TestScanVaultCanceled(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] Task\n")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := ScanVaultWithOptions(ctx, []string{vault}, nil, ScanOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReadLine(t *testing.T) {
+	long := strings.Repeat("x", 20)
+
+	testCases := []struct {
+		name    string
+		input   string
+		lines   []string
+		tooLong []int
+	}{
+		{name: "empty", input: ""},
+		{name: "no final newline", input: "a\nb", lines: []string{"a", "b"}},
+		{name: "CRLF", input: "a\r\nb\r\n", lines: []string{"a", "b"}},
+		{name: "blank lines", input: "\n\na\n", lines: []string{"", "", "a"}},
+		{name: "long line", input: "a\n" + long + "\nb\n", lines: []string{"a", "", "b"}, tooLong: []int{1}},
+		{name: "long last line", input: "a\n" + long, lines: []string{"a", ""}, tooLong: []int{1}},
+		{name: "long lines", input: long + "\n" + long + "\n", lines: []string{"", ""}, tooLong: []int{0, 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// the smallest buffer bufio allows
+			r := bufio.NewReaderSize(strings.NewReader(tc.input), 16)
+
+			var (
+				lines   []string
+				tooLong []int
+			)
+
+			for {
+				line, long, err := readLine(r)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				require.NoError(t, err)
+
+				if long {
+					tooLong = append(tooLong, len(lines))
+				}
+
+				lines = append(lines, line)
+			}
+
+			assert.Equal(t, tc.lines, lines)
+			assert.Equal(t, tc.tooLong, tooLong)
+		})
+	}
+}
+
+func TestScanVaultWarnings(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] Before\n- [ ] "+strings.Repeat("x", maxLineLength)+"\n- [ ] After\n")
+	writeNote(t, vault, "other.md", "- [ ] Other\n")
+	require.NoError(t, os.Symlink(filepath.Join(vault, "missing"), filepath.Join(vault, "broken.md")))
+	require.NoError(t, os.Symlink(t.TempDir(), filepath.Join(vault, "folder.md")))
+
+	result, err := ScanVault([]string{vault}, nil)
+	require.NoError(t, err)
+
+	var descriptions []string
+	for _, task := range result.Tasks {
+		descriptions = append(descriptions, task.Description)
+	}
+
+	// everything readable is still returned
+	assert.Equal(t, []string{"Other", "Before", "After"}, descriptions)
+	assert.Equal(t, 3, result.Tasks[2].LineNumber)
+
+	assert.Equal(t, []ScanWarning{
+		{File: "broken.md", Kind: WarningUnreadableFile, Message: "no such file or directory"},
+		{File: "folder.md", Kind: WarningUnreadableFile, Message: "is a directory"},
+		{File: "todo.md", Kind: WarningLineTooLong, Message: "line 2 is longer than 65536 bytes and was skipped"},
+	}, result.Warnings)
+}
+
+func TestScanVaultUnreadableFolder(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions don't apply to root")
+	}
+
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] Task\n")
+	writeNote(t, vault, "Private/secret.md", "- [ ] Secret\n")
+
+	private := filepath.Join(vault, "Private")
+	require.NoError(t, os.Chmod(private, 0o000))
+	t.Cleanup(func() { _ = os.Chmod(private, 0o755) })
+
+	result, err := ScanVault([]string{vault}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Tasks, 1)
+	assert.Equal(t, "Task", result.Tasks[0].Description)
+	assert.Equal(t, []ScanWarning{
+		{File: "Private", Kind: WarningUnreadableDirectory, Message: "permission denied"},
+	}, result.Warnings)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// tasksPluginSettingsPath is where the Obsidian Tasks plugin stores its
+// settings, relative to the vault's configuration folder. It is slash
+// separated; filepath.Join converts it for the platform.
+const tasksPluginSettingsPath = "plugins/obsidian-tasks-plugin/data.json"
+
+// VaultSettings holds the Tasks plugin settings of a vault that change how
+// tasks are read and written. A nil *VaultSettings holds the plugin's
+// defaults.
+type VaultSettings struct {
+	// Statuses holds the core and custom statuses configured in the plugin
+	Statuses *StatusRegistry
+	// GlobalFilter, if set, must appear in a checklist item for it to be a
+	// task, e.g. "#task"
+	GlobalFilter string
+	// RemoveGlobalFilter removes the global filter from task descriptions
+	RemoveGlobalFilter bool
+	// SetDoneDate adds a ✅ done date when a task is completed
+	SetDoneDate bool
+	// SetCreatedDate adds a ➕ created date to new tasks
+	SetCreatedDate bool
+}
+
+// tasksPluginData is the subset of the Tasks plugin's data.json we read
+type tasksPluginData struct {
+	SetDoneDate    *bool  `json:"setDoneDate"`
+	GlobalFilter   string `json:"globalFilter"`
+	StatusSettings struct {
+		CoreStatuses   []tasksPluginStatus `json:"coreStatuses"`
+		CustomStatuses []tasksPluginStatus `json:"customStatuses"`
+	} `json:"statusSettings"`
+	RemoveGlobalFilter bool `json:"removeGlobalFilter"`
+	SetCreatedDate     bool `json:"setCreatedDate"`
+}
+
+type tasksPluginStatus struct {
+	Symbol           string `json:"symbol"`
+	Name             string `json:"name"`
+	NextStatusSymbol string `json:"nextStatusSymbol"`
+	Type             string `json:"type"`
+}
+
+// LoadVaultSettings reads the Tasks plugin settings of the vault containing
+// dir: the nearest of dir and its parents with an .obsidian configuration
+// folder. Without a vault or the plugin's settings file, the plugin's
+// defaults apply.
+func LoadVaultSettings(dir string) (*VaultSettings, error) {
+	configDir, ok := findConfigDir(dir)
+	if !ok {
+		return nil, nil
+	}
+
+	path := filepath.Join(configDir, tasksPluginSettingsPath)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Tasks plugin settings: %w", err)
+	}
+
+	var raw tasksPluginData
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid Tasks plugin settings in %q: %w", path, err)
+	}
+
+	settings := &VaultSettings{
+		GlobalFilter:       strings.TrimSpace(raw.GlobalFilter),
+		RemoveGlobalFilter: raw.RemoveGlobalFilter,
+		SetDoneDate:        raw.SetDoneDate == nil || *raw.SetDoneDate,
+		SetCreatedDate:     raw.SetCreatedDate,
+	}
+
+	var statuses []Status
+
+	for _, s := range append(raw.StatusSettings.CoreStatuses, raw.StatusSettings.CustomStatuses...) {
+		statusType, err := parseStatusType(s.Type)
+
+		// the plugin's settings UI can leave incomplete rows behind; skip
+		// them rather than rejecting the vault
+		if err != nil || utf8.RuneCountInString(s.Symbol) != 1 {
+			continue
+		}
+
+		statuses = append(statuses, Status{Symbol: s.Symbol, Name: s.Name, NextSymbol: s.NextStatusSymbol, Type: statusType})
+	}
+
+	if len(statuses) > 0 {
+		settings.Statuses = NewStatusRegistry(statuses...)
+	}
+
+	return settings, nil
+}
+
+// findConfigDir returns the .obsidian folder of the vault containing dir
+func findConfigDir(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		configDir := filepath.Join(dir, ".obsidian")
+		if info, err := os.Stat(configDir); err == nil && info.IsDir() {
+			return configDir, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+
+		dir = parent
+	}
+}
+
+// statuses returns the status registry, which is nil (holding only the core
+// statuses) for a nil *VaultSettings
+func (s *VaultSettings) statuses() *StatusRegistry {
+	if s == nil {
+		return nil
+	}
+
+	return s.Statuses
+}
+
+// doneDate returns the done date to set when a task is completed on day, or
+// "" if done dates are turned off
+func (s *VaultSettings) doneDate(day string) string {
+	if s != nil && !s.SetDoneDate {
+		return ""
+	}
+
+	return day
+}
+
+// isTask reports whether the content of a checklist item (the text after the
+// checkbox) makes it a task: it must contain the global filter, if any
+func (s *VaultSettings) isTask(content string) bool {
+	return s == nil || s.GlobalFilter == "" || strings.Contains(content, s.GlobalFilter)
+}
+
+// isGlobalFilterTag reports whether tag (without its #) is the global filter
+func (s *VaultSettings) isGlobalFilterTag(tag string) bool {
+	return s != nil && s.GlobalFilter == "#"+tag
+}
+
+// withGlobalFilterTag adds the global filter to a task's new set of tags if
+// the filter is a tag. Tasks don't list it among their tags, so replacing
+// them would otherwise drop it.
+func (s *VaultSettings) withGlobalFilterTag(tags []string) []string {
+	if s == nil {
+		return tags
+	}
+
+	tag := strings.TrimPrefix(s.GlobalFilter, "#")
+	if !s.isGlobalFilterTag(tag) || slices.Contains(tags, tag) {
+		return tags
+	}
+
+	return append(slices.Clip(tags), tag)
+}
+
+// removeGlobalFilter removes the global filter from a task description, if
+// the plugin is set to do so
+func (s *VaultSettings) removeGlobalFilter(description string) string {
+	if s == nil || s.GlobalFilter == "" || !s.RemoveGlobalFilter {
+		return description
+	}
+
+	return strings.Join(strings.Fields(strings.Replace(description, s.GlobalFilter, "", 1)), " ")
+}
+
+// withGlobalFilter prepends the global filter to a new task's description,
+// so that the task is recognized as a task
+func (s *VaultSettings) withGlobalFilter(description string) string {
+	if s.isTask(description) {
+		return description
+	}
+
+	return s.GlobalFilter + " " + description
+}
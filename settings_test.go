@@ -0,0 +1,213 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTasksSettings writes the Tasks plugin's data.json into the vault
+func writeTasksSettings(t *testing.T, vault, data string) {
+	t.Helper()
+
+	path := filepath.Join(vault, ".obsidian", tasksPluginSettingsPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+}
+
+func TestLoadVaultSettings(t *testing.T) {
+	vault := t.TempDir()
+
+	settings, err := LoadVaultSettings(vault)
+	require.NoError(t, err)
+	assert.Nil(t, settings)
+
+	writeTasksSettings(t, vault, `{
+		"globalFilter": " #task ",
+		"removeGlobalFilter": true,
+		"setDoneDate": false,
+		"setCreatedDate": true,
+		"statusSettings": {
+			"coreStatuses": [
+				{"symbol": " ", "name": "To do", "nextStatusSymbol": "/", "type": "TODO"}
+			],
+			"customStatuses": [
+				{"symbol": "!", "name": "Important", "nextStatusSymbol": "x", "type": "TODO"},
+				{"symbol": "d", "name": "Delegated", "nextStatusSymbol": " ", "type": "DONE"},
+				{"symbol": "", "name": "", "nextStatusSymbol": "", "type": "TODO"},
+				{"symbol": "?", "name": "Broken", "nextStatusSymbol": "x", "type": "EMPTY"}
+			]
+		}
+	}`)
+
+	// settings are found from folders inside the vault too
+	sub := filepath.Join(vault, "Projects")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+
+	settings, err = LoadVaultSettings(sub)
+	require.NoError(t, err)
+	require.NotNil(t, settings)
+	assert.Equal(t, "#task", settings.GlobalFilter)
+	assert.True(t, settings.RemoveGlobalFilter)
+	assert.False(t, settings.SetDoneDate)
+	assert.True(t, settings.SetCreatedDate)
+
+	assert.Equal(t, Status{Symbol: " ", Name: "To do", NextSymbol: "/", Type: StatusTypeTodo}, settings.Statuses.Lookup(" "))
+	assert.Equal(t, "Important", settings.Statuses.Lookup("!").Name)
+	assert.Equal(t, StatusTypeDone, settings.Statuses.Lookup("d").Type)
+	assert.Equal(t, "Unknown", settings.Statuses.Lookup("?").Name)
+	assert.Equal(t, "Done", settings.Statuses.Lookup("x").Name)
+
+	t.Run("defaults", func(t *testing.T) {
+		vault := t.TempDir()
+		writeTasksSettings(t, vault, `{}`)
+
+		settings, err := LoadVaultSettings(vault)
+		require.NoError(t, err)
+		assert.Equal(t, &VaultSettings{SetDoneDate: true}, settings)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		vault := t.TempDir()
+		writeTasksSettings(t, vault, `{"globalFilter": `)
+
+		_, err := LoadVaultSettings(vault)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid Tasks plugin settings")
+	})
+}
+
+func TestParseTaskWithSettings(t *testing.T) {
+	tag := &VaultSettings{GlobalFilter: "#task", SetDoneDate: true}
+
+	assert.Nil(t, ParseTaskWithSettings("- [ ] Milk", "list.md", 1, tag))
+
+	task := ParseTaskWithSettings("- [ ] #task Call Bob #phone", "todo.md", 2, tag)
+	require.NotNil(t, task)
+	assert.Equal(t, "Call Bob", task.Description)
+	assert.Equal(t, []string{"phone"}, task.Tags)
+
+	word := &VaultSettings{GlobalFilter: "TODO"}
+	task = ParseTaskWithSettings("- [ ] TODO Call  Bob", "todo.md", 3, word)
+	require.NotNil(t, task)
+	assert.Equal(t, "TODO Call  Bob", task.Description)
+
+	word.RemoveGlobalFilter = true
+	task = ParseTaskWithSettings("- [ ] Call TODO Bob", "todo.md", 3, word)
+	require.NotNil(t, task)
+	assert.Equal(t, "Call Bob", task.Description)
+
+	statuses := &VaultSettings{Statuses: NewStatusRegistry(Status{Symbol: "d", Name: "Delegated", Type: StatusTypeDone})}
+	task = ParseTaskWithSettings("- [d] Review", "todo.md", 4, statuses)
+	require.NotNil(t, task)
+	assert.Equal(t, "Delegated", task.StatusName)
+	assert.Equal(t, "complete", task.Status)
+}
+
+func TestVaultSettingsWriteTools(t *testing.T) {
+	vault := t.TempDir()
+	writeTasksSettings(t, vault, `{
+		"globalFilter": "#task",
+		"setDoneDate": false,
+		"setCreatedDate": true,
+		"statusSettings": {"customStatuses": [
+			{"symbol": "!", "name": "Important", "nextStatusSymbol": "d", "type": "TODO"},
+			{"symbol": "d", "name": "Delegated", "nextStatusSymbol": " ", "type": "DONE"}
+		]}
+	}`)
+
+	file := filepath.Join(vault, "todo.md")
+	require.NoError(t, os.WriteFile(file, []byte("- [!] #task Urgent thing\n- [ ] Shopping list item\n"), 0o600))
+
+	s := testServer(t, vault)
+
+	result, err := ScanVault([]string{vault}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Tasks, 1)
+	assert.Equal(t, "Important", result.Tasks[0].StatusName)
+
+	res, out, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "todo.md:1", Description: "Urgent thing"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	assert.Equal(t, "Delegated", out.Task.StatusName)
+	assert.Empty(t, out.Task.DoneDate)
+
+	// checklist items without the global filter are not tasks
+	res, _, err = s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "todo.md:2", Description: "Shopping list item"})
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.True(t, res.IsError)
+
+	res, created, err := s.createTask(t.Context(), nil, CreateTaskInput{Description: "New thing", Path: "todo.md"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.NotNil(t, created.Task)
+	assert.Equal(t, "New thing", created.Task.Description)
+	assert.Equal(t, "2026-10-16", created.Task.CreatedDate)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "- [d] #task Urgent thing\n- [ ] Shopping list item\n- [ ] #task New thing ➕ 2026-10-16\n", string(data))
+}
+
+func TestUpdateTaskGlobalFilter(t *testing.T) {
+	t.Run("tag", func(t *testing.T) {
+		vault := t.TempDir()
+		writeTasksSettings(t, vault, `{"globalFilter": "#task"}`)
+
+		file := filepath.Join(vault, "todo.md")
+		require.NoError(t, os.WriteFile(file, []byte("- [ ] #task Call Bob #phone 🔁 every week 📅 2026-10-01\n"), 0o600))
+
+		s := testServer(t, vault)
+		tags := []string{"work"}
+		desc := "Call Alice"
+		done := "x"
+
+		res, out, err := s.updateTask(t.Context(), nil, UpdateTaskInput{ID: "todo.md:1", ExpectedDescription: "Call Bob", Tags: &tags})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		require.NotNil(t, out.Task)
+		assert.Equal(t, []string{"work"}, out.Task.Tags)
+
+		res, _, err = s.updateTask(t.Context(), nil, UpdateTaskInput{ID: "todo.md:1", ExpectedDescription: "Call Bob", Description: &desc})
+		require.NoError(t, err)
+		require.Nil(t, res)
+
+		res, out, err = s.updateTask(t.Context(), nil, UpdateTaskInput{ID: "todo.md:1", ExpectedDescription: "Call Alice", Status: &done})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		require.NotNil(t, out.NextOccurrence)
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, "- [ ] Call Alice #task #work 🔁 every week 📅 2026-10-08\n"+
+			"- [x] Call Alice #task #work 🔁 every week 📅 2026-10-01 ✅ 2026-10-16\n", string(data))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		vault := t.TempDir()
+		writeTasksSettings(t, vault, `{"globalFilter": "TODO", "removeGlobalFilter": true}`)
+
+		file := filepath.Join(vault, "todo.md")
+		require.NoError(t, os.WriteFile(file, []byte("- [ ] TODO Call Bob #phone\n"), 0o600))
+
+		s := testServer(t, vault)
+		desc := "Call Alice"
+		tags := []string{}
+
+		res, out, err := s.updateTask(t.Context(), nil, UpdateTaskInput{
+			ID: "todo.md:1", ExpectedDescription: "Call Bob", Description: &desc, Tags: &tags,
+		})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		require.NotNil(t, out.Task)
+		assert.Equal(t, "Call Alice", out.Task.Description)
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, "- [ ] TODO Call Alice\n", string(data))
+	})
+}
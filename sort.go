@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cmp"
+	"path/filepath"
+	"strings"
+)
+
+// sortFields maps the field names accepted by "sort by" to sort fields
+func sortFields() map[string]SortField {
+	return map[string]SortField{
+		"priority":    SortByPriority,
+		"due":         SortByDue,
+		"urgency":     SortByUrgency,
+		"path":        SortByPath,
+		"filename":    SortByFilename,
+		"description": SortByDescription,
+		"status":      SortByStatus,
+		"tag":         SortByTag,
+		"scheduled":   SortByScheduled,
+		"start":       SortByStart,
+		"done":        SortByDone,
+		"created":     SortByCreated,
+		"cancelled":   SortByCancelled,
+		"happens":     SortByHappens,
+		"heading":     SortByHeading,
+	}
+}
+
+// compareBySortKey compares two tasks by a single sort key. When exactly one
+// of the tasks lacks the field (e.g. has no due date), that task sorts last
+// whatever the direction, and final is true to say the result must not be
+// reversed.
+//
+//nolint:gocyclo // one case per sort field
+func compareBySortKey(key SortKey, a, b *Task) (c int, final bool) {
+	switch key.Field {
+	case SortByPriority:
+		switch {
+		case a.Priority == PriorityNone && b.Priority == PriorityNone:
+			return 0, false
+		case a.Priority == PriorityNone:
+			return 1, true
+		case b.Priority == PriorityNone:
+			return -1, true
+		default:
+			return cmp.Compare(a.Priority, b.Priority), false
+		}
+	case SortByUrgency:
+		// most urgent first
+		return cmp.Compare(b.Urgency, a.Urgency), false
+	case SortByDue:
+		return compareOptional(a.DueDate, b.DueDate)
+	case SortByScheduled:
+		return compareOptional(a.ScheduledDate, b.ScheduledDate)
+	case SortByStart:
+		return compareOptional(a.StartDate, b.StartDate)
+	case SortByDone:
+		return compareOptional(a.DoneDate, b.DoneDate)
+	case SortByCreated:
+		return compareOptional(a.CreatedDate, b.CreatedDate)
+	case SortByCancelled:
+		return compareOptional(a.CancelledDate, b.CancelledDate)
+	case SortByHappens:
+		return compareOptional(happensDate(a), happensDate(b))
+	case SortByPath:
+		return cmp.Compare(a.FilePath, b.FilePath), false
+	case SortByFilename:
+		return cmp.Compare(filepath.Base(a.FilePath), filepath.Base(b.FilePath)), false
+	case SortByDescription:
+		return cmp.Compare(strings.ToLower(a.Description), strings.ToLower(b.Description)), false
+	case SortByStatus:
+		// tasks that are not done first
+		return compareBool(a.StatusType.IsDone(), b.StatusType.IsDone()), false
+	case SortByTag:
+		return compareOptional(strings.ToLower(nthTag(a, key.TagIndex)), strings.ToLower(nthTag(b, key.TagIndex)))
+	case SortByHeading:
+		return compareOptional(a.Heading, b.Heading)
+	default:
+		return 0, false
+	}
+}
+
+// compareOptional compares two optional values, ordering empty values last.
+// final is true if exactly one value is empty.
+func compareOptional(a, b string) (int, bool) {
+	switch {
+	case a == "" && b == "":
+		return 0, false
+	case a == "":
+		return 1, true
+	case b == "":
+		return -1, true
+	default:
+		return cmp.Compare(a, b), false
+	}
+}
+
+// compareBool orders false before true
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// happensDate returns the earliest of a task's start, scheduled and due
+// dates, or "" if it has none
+func happensDate(task *Task) string {
+	earliest := ""
+
+	for _, date := range []string{task.StartDate, task.ScheduledDate, task.DueDate} {
+		if date != "" && (earliest == "" || date < earliest) {
+			earliest = date
+		}
+	}
+
+	return earliest
+}
+
+// nthTag returns a task's nth tag (counting from 1), or "" if it has fewer
+func nthTag(task *Task, n int) string {
+	if n < 1 || n > len(task.Tags) {
+		return ""
+	}
+
+	return task.Tags[n-1]
+}
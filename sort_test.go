@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortTasksByField(t *testing.T) {
+	tasks := []*Task{
+		{Description: "banana", FilePath: "b/two.md", LineNumber: 1, Tags: []string{"x", "Beta"},
+			StartDate: "2026-10-05", Heading: "Later", StatusType: StatusTypeDone},
+		{Description: "Apple", FilePath: "a/zzz.md", LineNumber: 1, Tags: []string{"y"},
+			ScheduledDate: "2026-10-03", DoneDate: "2026-10-01", StatusType: StatusTypeTodo},
+		{Description: "cherry", FilePath: "c/one.md", LineNumber: 1, Tags: []string{"z", "alpha"},
+			DueDate: "2026-10-04", CreatedDate: "2026-09-01", Heading: "Earlier", StatusType: StatusTypeTodo},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "sort by path", want: []string{"Apple", "banana", "cherry"}},
+		{query: "sort by filename", want: []string{"cherry", "banana", "Apple"}},
+		{query: "sort by description", want: []string{"Apple", "banana", "cherry"}},
+		{query: "sort by description reverse", want: []string{"cherry", "banana", "Apple"}},
+		{query: "sort by status", want: []string{"Apple", "cherry", "banana"}},
+		{query: "sort by tag", want: []string{"banana", "Apple", "cherry"}},
+		{query: "sort by tag 2", want: []string{"cherry", "banana", "Apple"}},
+		{query: "sort by tag 2 reverse", want: []string{"banana", "cherry", "Apple"}},
+		{query: "sort by tag reverse 2", want: []string{"banana", "cherry", "Apple"}},
+		{query: "sort by scheduled", want: []string{"Apple", "banana", "cherry"}},
+		{query: "sort by start", want: []string{"banana", "Apple", "cherry"}},
+		{query: "sort by done", want: []string{"Apple", "banana", "cherry"}},
+		{query: "sort by created reverse", want: []string{"cherry", "Apple", "banana"}},
+		{query: "sort by happens", want: []string{"Apple", "cherry", "banana"}},
+		{query: "sort by happens reverse", want: []string{"banana", "cherry", "Apple"}},
+		{query: "sort by heading", want: []string{"cherry", "banana", "Apple"}},
+		{query: "sort by heading reverse", want: []string{"banana", "cherry", "Apple"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			sorted := append([]*Task(nil), tasks...)
+			sortTasks(sorted, query)
+
+			got := make([]string, 0, len(sorted))
+			for _, task := range sorted {
+				got = append(got, task.Description)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSortKeyErrors(t *testing.T) {
+	_, err := ParseQuery("sort by due 2")
+	require.Error(t, err)
+
+	_, err = ParseQuery("sort by tag 0")
+	require.Error(t, err)
+
+	_, err = ParseQuery("sort by tag 1 reverse 2")
+	require.Error(t, err)
+
+	_, err = ParseQuery("sort by heading")
+	require.NoError(t, err)
+
+	_, err = ParseQuery("sort by hapens")
+
+	var qerr *QueryError
+	require.ErrorAs(t, err, &qerr)
+	assert.Equal(t, "sort by happens", qerr.Errors[0].Suggestion)
+}
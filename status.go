@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatusType classifies a status symbol, mirroring the Tasks plugin's status types
+type StatusType string
+
+const (
+	StatusTypeTodo       StatusType = "TODO"
+	StatusTypeInProgress StatusType = "IN_PROGRESS"
+	StatusTypeDone       StatusType = "DONE"
+	StatusTypeCancelled  StatusType = "CANCELLED"
+	StatusTypeNonTask    StatusType = "NON_TASK"
+)
+
+// parseStatusType parses a status type name such as "in_progress" or "DONE"
+func parseStatusType(s string) (StatusType, error) {
+	t := StatusType(strings.ToUpper(strings.TrimSpace(s)))
+	switch t {
+	case StatusTypeTodo, StatusTypeInProgress, StatusTypeDone, StatusTypeCancelled, StatusTypeNonTask:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown status type %q", s)
+	}
+}
+
+// IsDone reports whether tasks of this type count as done, as the Tasks
+// plugin's "done" filter does
+func (t StatusType) IsDone() bool {
+	return t == StatusTypeDone || t == StatusTypeCancelled || t == StatusTypeNonTask
+}
+
+// Status describes a single checkbox symbol
+type Status struct {
+	Symbol     string
+	Name       string
+	NextSymbol string
+	Type       StatusType
+}
+
+// StatusRegistry maps checkbox symbols to statuses. A nil registry contains
+// only the core statuses.
+type StatusRegistry struct {
+	statuses map[string]Status
+}
+
+// NewStatusRegistry returns a registry containing the core statuses plus the
+// given custom statuses, which take precedence
+func NewStatusRegistry(custom ...Status) *StatusRegistry {
+	r := &StatusRegistry{statuses: make(map[string]Status, len(custom))}
+	for _, s := range custom {
+		r.statuses[s.Symbol] = s
+	}
+
+	return r
+}
+
+// Lookup returns the status for the given symbol. Unrecognized symbols are
+// treated as TODO, as the Tasks plugin does.
+func (r *StatusRegistry) Lookup(symbol string) Status {
+	if r != nil {
+		if s, ok := r.statuses[symbol]; ok {
+			return s
+		}
+	}
+
+	if s, ok := coreStatus(symbol); ok {
+		return s
+	}
+
+	return Status{Symbol: symbol, Name: "Unknown", NextSymbol: "x", Type: StatusTypeTodo}
+}
+
+func coreStatus(symbol string) (Status, bool) {
+	switch symbol {
+	case " ":
+		return Status{Symbol: symbol, Name: "Todo", NextSymbol: "x", Type: StatusTypeTodo}, true
+	case "x", "X":
+		return Status{Symbol: symbol, Name: "Done", NextSymbol: " ", Type: StatusTypeDone}, true
+	case "/":
+		return Status{Symbol: symbol, Name: "In Progress", NextSymbol: "x", Type: StatusTypeInProgress}, true
+	case "-":
+		return Status{Symbol: symbol, Name: "Cancelled", NextSymbol: " ", Type: StatusTypeCancelled}, true
+	case ">":
+		return Status{Symbol: symbol, Name: "Forwarded", NextSymbol: "x", Type: StatusTypeTodo}, true
+	default:
+		return Status{}, false
+	}
+}
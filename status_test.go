@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusRegistryLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		symbol   string
+		wantName string
+		wantType StatusType
+	}{
+		{name: "todo", symbol: " ", wantName: "Todo", wantType: StatusTypeTodo},
+		{name: "done", symbol: "x", wantName: "Done", wantType: StatusTypeDone},
+		{name: "upper-case done", symbol: "X", wantName: "Done", wantType: StatusTypeDone},
+		{name: "in progress", symbol: "/", wantName: "In Progress", wantType: StatusTypeInProgress},
+		{name: "cancelled", symbol: "-", wantName: "Cancelled", wantType: StatusTypeCancelled},
+		{name: "forwarded", symbol: ">", wantName: "Forwarded", wantType: StatusTypeTodo},
+		{name: "unknown", symbol: "?", wantName: "Unknown", wantType: StatusTypeTodo},
+	}
+
+	var registry *StatusRegistry
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := registry.Lookup(tt.symbol)
+			assert.Equal(t, tt.symbol, got.Symbol)
+			assert.Equal(t, tt.wantName, got.Name)
+			assert.Equal(t, tt.wantType, got.Type)
+		})
+	}
+}
+
+func TestStatusRegistryCustom(t *testing.T) {
+	registry := NewStatusRegistry(
+		Status{Symbol: "!", Name: "Important", NextSymbol: "x", Type: StatusTypeTodo},
+		Status{Symbol: "-", Name: "Dropped", NextSymbol: " ", Type: StatusTypeNonTask},
+	)
+
+	assert.Equal(t, "Important", registry.Lookup("!").Name)
+	assert.Equal(t, StatusTypeNonTask, registry.Lookup("-").Type)
+	assert.Equal(t, "Done", registry.Lookup("x").Name)
+}
+
+func TestParseStatusType(t *testing.T) {
+	got, err := parseStatusType("in_progress")
+	require.NoError(t, err)
+	assert.Equal(t, StatusTypeInProgress, got)
+
+	_, err = parseStatusType("SOMEDAY")
+	require.Error(t, err)
+}
+
+func TestStatusTypeIsDone(t *testing.T) {
+	assert.False(t, StatusTypeTodo.IsDone())
+	assert.False(t, StatusTypeInProgress.IsDone())
+	assert.True(t, StatusTypeDone.IsDone())
+	assert.True(t, StatusTypeCancelled.IsDone())
+	assert.True(t, StatusTypeNonTask.IsDone())
+}
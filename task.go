@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,23 +20,89 @@ const (
 
 // Task represents a parsed Obsidian task
 type Task struct {
-	ID          string   `json:"id"`
-	Description string   `json:"description"`
-	Status      string   `json:"status"`
-	FilePath    string   `json:"filePath"`
-	DueDate     string   `json:"dueDate,omitempty"`
-	Tags        []string `json:"tags"`
-	LineNumber  int      `json:"lineNumber"`
-	Priority    Priority `json:"priority"`
+	ID            string     `json:"id"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"`
+	StatusSymbol  string     `json:"statusSymbol"`
+	StatusName    string     `json:"statusName"`
+	StatusType    StatusType `json:"statusType"`
+	FilePath      string     `json:"filePath"`
+	DueDate       string     `json:"dueDate,omitempty"`
+	ScheduledDate string     `json:"scheduledDate,omitempty"`
+	StartDate     string     `json:"startDate,omitempty"`
+	CreatedDate   string     `json:"createdDate,omitempty"`
+	DoneDate      string     `json:"doneDate,omitempty"`
+	CancelledDate string     `json:"cancelledDate,omitempty"`
+	Recurrence    string     `json:"recurrence,omitempty"`
+	// Heading is the text of the closest heading above the task
+	Heading string `json:"heading,omitempty"`
+	// DependencyID is the task's 🆔 identifier, which other tasks list in
+	// DependsOn (⛔) to mark themselves as blocked by it
+	DependencyID string   `json:"dependencyId,omitempty"`
+	DependsOn    []string `json:"dependsOn,omitempty"`
+	Blocked      bool     `json:"blocked,omitempty"`
+	Blocking     bool     `json:"blocking,omitempty"`
+	// ParentID is the id of the list item this task is nested under, which
+	// may be a task or a plain list item (see ParentIsTask)
+	ParentID     string   `json:"parentId,omitempty"`
+	ParentIsTask bool     `json:"parentIsTask,omitempty"`
+	ChildIDs     []string `json:"childIds,omitempty"`
+	Tags         []string `json:"tags"`
+	IndentLevel  int      `json:"indentLevel"`
+	LineNumber   int      `json:"lineNumber"`
+	Priority     Priority `json:"priority"`
+	// Urgency is the Tasks plugin's urgency score, computed when scanning
+	Urgency float64 `json:"urgency"`
+
+	// ancestorIDs lists the ids of every enclosing list item, nearest first
+	ancestorIDs []string
 }
 
+// tagPattern matches a tag name as Obsidian does: Unicode letters and
+// digits, underscores, hyphens and slashes for nested tags (e.g.
+// work/clientA), with at least one character that is not a digit
+const tagPattern = `\p{N}*[\p{L}\p{M}_/-][\p{L}\p{M}\p{N}_/-]*`
+
 var (
-	taskRegex     = regexp.MustCompile(`^(\s*)- \[([ x])\](.*)$`)
-	tagRegex      = regexp.MustCompile(`#[\w-]+`)
+	taskRegex     = regexp.MustCompile(`^(\s*)- \[(.)\](.*)$`)
+	tagRegex      = regexp.MustCompile(`#` + tagPattern)
 	dueDateRegex  = regexp.MustCompile(`(?:📅|🗓️)\s*(\d{4}-\d{2}-\d{2})`)
 	priorityRegex = regexp.MustCompile(`[🔺⏫🔼🔽]`)
+
+	scheduledDateRegex = regexp.MustCompile(`(?:⏳|⌛)\s*(\d{4}-\d{2}-\d{2})`)
+	startDateRegex     = regexp.MustCompile(`🛫\s*(\d{4}-\d{2}-\d{2})`)
+	createdDateRegex   = regexp.MustCompile(`➕\s*(\d{4}-\d{2}-\d{2})`)
+	doneDateRegex      = regexp.MustCompile(`✅\s*(\d{4}-\d{2}-\d{2})`)
+	cancelledDateRegex = regexp.MustCompile(`❌\s*(\d{4}-\d{2}-\d{2})`)
+	recurrenceRegex    = regexp.MustCompile(`🔁\s*([a-zA-Z0-9, !]+)`)
+	dependencyIDRegex  = regexp.MustCompile(`🆔\s*([a-zA-Z0-9_-]+)`)
+	dependsOnRegex     = regexp.MustCompile(`⛔\s*([a-zA-Z0-9_-]+(?:\s*,\s*[a-zA-Z0-9_-]+)*)`)
 )
 
+// dateRegexes lists every date signifier regex, used to strip dates from the
+// description
+func dateRegexes() []*regexp.Regexp {
+	return []*regexp.Regexp{
+		dueDateRegex,
+		scheduledDateRegex,
+		startDateRegex,
+		createdDateRegex,
+		doneDateRegex,
+		cancelledDateRegex,
+	}
+}
+
+// extractDate returns the first date (or other value) captured by re in
+// content, or "" if none
+func extractDate(re *regexp.Regexp, content string) string {
+	matches := re.FindStringSubmatch(content)
+	if len(matches) < 2 {
+		return ""
+	}
+
+	return matches[1]
+}
+
 func parsePriority(content string) Priority {
 	if strings.Contains(content, "🔺") {
 		return PriorityHighest
@@ -56,58 +123,181 @@ func parsePriority(content string) Priority {
 	return PriorityNone
 }
 
-// ParseTask parses a markdown task line into a Task struct
+// Emoji returns the emoji that marks this priority on a task line, or "" for
+// PriorityNone
+func (p Priority) Emoji() string {
+	switch p {
+	case PriorityHighest:
+		return "🔺"
+	case PriorityHigh:
+		return "⏫"
+	case PriorityMedium:
+		return "🔼"
+	case PriorityLow:
+		return "🔽"
+	default:
+		return ""
+	}
+}
+
+// parsePriorityName parses a priority name such as "high" or "none"
+func parsePriorityName(name string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "highest":
+		return PriorityHighest, nil
+	case "high":
+		return PriorityHigh, nil
+	case "medium":
+		return PriorityMedium, nil
+	case "low":
+		return PriorityLow, nil
+	case "none", "normal", "":
+		return PriorityNone, nil
+	default:
+		return PriorityNone, fmt.Errorf("unknown priority %q", name)
+	}
+}
+
+// FormatTask renders a task as a markdown task line in the Tasks emoji
+// format, such that ParseTask returns the same fields. Fields are written in
+// the order the Tasks plugin uses.
+func FormatTask(t *Task) string {
+	symbol := t.StatusSymbol
+	if symbol == "" {
+		symbol = " "
+	}
+
+	parts := []string{"- [" + symbol + "]"}
+
+	if t.Description != "" {
+		parts = append(parts, t.Description)
+	}
+
+	for _, tag := range t.Tags {
+		parts = append(parts, "#"+tag)
+	}
+
+	if t.DependencyID != "" {
+		parts = append(parts, "🆔 "+t.DependencyID)
+	}
+
+	if len(t.DependsOn) > 0 {
+		parts = append(parts, "⛔ "+strings.Join(t.DependsOn, ","))
+	}
+
+	if emoji := t.Priority.Emoji(); emoji != "" {
+		parts = append(parts, emoji)
+	}
+
+	for _, field := range []struct{ emoji, date string }{
+		{"🔁", t.Recurrence},
+		{"➕", t.CreatedDate},
+		{"🛫", t.StartDate},
+		{"⏳", t.ScheduledDate},
+		{"📅", t.DueDate},
+		{"❌", t.CancelledDate},
+		{"✅", t.DoneDate},
+	} {
+		if field.date != "" {
+			parts = append(parts, field.emoji+" "+field.date)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// parseDependsOn returns the ids listed after ⛔ signifiers in content
+func parseDependsOn(content string) []string {
+	var ids []string
+
+	for _, m := range dependsOnRegex.FindAllStringSubmatch(content, -1) {
+		for id := range strings.SplitSeq(m[1], ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
+
+// taskID returns the id of the list item on the given line of a file
+func taskID(filePath string, lineNumber int) string {
+	return filePath + ":" + strconv.Itoa(lineNumber)
+}
+
+// ParseTask parses a markdown task line into a Task struct, using the Tasks
+// plugin's default settings
 func ParseTask(line string, filePath string, lineNumber int) *Task {
+	return ParseTaskWithSettings(line, filePath, lineNumber, nil)
+}
+
+// ParseTaskWithSettings parses a markdown task line into a Task struct,
+// applying a vault's Tasks plugin settings. It returns nil if the line is not
+// a task, including checklist items without the global filter.
+func ParseTaskWithSettings(line string, filePath string, lineNumber int, settings *VaultSettings) *Task {
 	matches := taskRegex.FindStringSubmatch(line)
-	if len(matches) < 4 {
+	if len(matches) < 4 || !settings.isTask(matches[3]) {
 		return nil
 	}
 
+	taskStatus := settings.statuses().Lookup(matches[2])
+
 	status := "incomplete"
-	if strings.TrimSpace(matches[2]) == "x" {
+	if taskStatus.Type.IsDone() {
 		status = "complete"
 	}
 
 	content := matches[3]
 
-	// Extract tags
-	tags := tagRegex.FindAllString(content, -1)
-	if tags == nil {
-		tags = []string{}
-	}
-	// Remove # prefix from tags
-	for i, tag := range tags {
-		tags[i] = strings.TrimPrefix(tag, "#")
-	}
-
-	// Extract due date
-	var dueDate string
+	// Extract tags, without the # prefix. The global filter is not a tag for
+	// querying, as in the Tasks plugin.
+	tags := []string{}
 
-	dueMatches := dueDateRegex.FindStringSubmatch(content)
-	if len(dueMatches) >= 2 {
-		dueDate = dueMatches[1]
+	for _, tag := range tagRegex.FindAllString(content, -1) {
+		if tag = strings.TrimPrefix(tag, "#"); !settings.isGlobalFilterTag(tag) {
+			tags = append(tags, tag)
+		}
 	}
 
 	// Extract priority
 	priority := parsePriority(content)
 
-	// Extract description (remove tags, due date markers, priority emojis)
+	// Extract description (remove tags, date markers, priority emojis)
 	description := content
 	description = tagRegex.ReplaceAllString(description, "")
-	description = dueDateRegex.ReplaceAllString(description, "")
+
+	for _, re := range dateRegexes() {
+		description = re.ReplaceAllString(description, "")
+	}
+
 	description = priorityRegex.ReplaceAllString(description, "")
-	description = strings.TrimSpace(description)
+	description = recurrenceRegex.ReplaceAllString(description, "")
+	description = dependencyIDRegex.ReplaceAllString(description, "")
+	description = dependsOnRegex.ReplaceAllString(description, "")
+	description = settings.removeGlobalFilter(strings.TrimSpace(description))
 
-	id := filePath + ":" + strconv.Itoa(lineNumber)
+	id := taskID(filePath, lineNumber)
 
 	return &Task{
-		ID:          id,
-		Description: description,
-		Status:      status,
-		FilePath:    filePath,
-		LineNumber:  lineNumber,
-		Tags:        tags,
-		DueDate:     dueDate,
-		Priority:    priority,
+		ID:            id,
+		Description:   description,
+		Status:        status,
+		StatusSymbol:  taskStatus.Symbol,
+		StatusName:    taskStatus.Name,
+		StatusType:    taskStatus.Type,
+		FilePath:      filePath,
+		LineNumber:    lineNumber,
+		Tags:          tags,
+		DueDate:       extractDate(dueDateRegex, content),
+		ScheduledDate: extractDate(scheduledDateRegex, content),
+		StartDate:     extractDate(startDateRegex, content),
+		CreatedDate:   extractDate(createdDateRegex, content),
+		DoneDate:      extractDate(doneDateRegex, content),
+		CancelledDate: extractDate(cancelledDateRegex, content),
+		Recurrence:    strings.TrimSpace(extractDate(recurrenceRegex, content)),
+		DependencyID:  extractDate(dependencyIDRegex, content),
+		DependsOn:     parseDependsOn(content),
+		Priority:      priority,
 	}
 }
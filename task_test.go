@@ -233,6 +233,178 @@ func TestParseTask(t *testing.T) {
 				DueDate:     "",
 			},
 		},
+		{
+			name:       "task with nested and Unicode tags",
+			line:       "- [ ] Task #2024 #work/clientA #café #日本語 #y2024",
+			filePath:   "todo.md",
+			lineNumber: 15,
+			want: &Task{
+				ID:          "todo.md:15",
+				Description: "Task #2024",
+				Status:      "incomplete",
+				FilePath:    "todo.md",
+				LineNumber:  15,
+				Tags:        []string{"work/clientA", "café", "日本語", "y2024"},
+			},
+		},
+		{
+			name:       "task with scheduled date",
+			line:       "- [ ] Plan trip ⏳ 2024-02-01",
+			filePath:   "todo.md",
+			lineNumber: 16,
+			want: &Task{
+				ID:            "todo.md:16",
+				Description:   "Plan trip",
+				Status:        "incomplete",
+				FilePath:      "todo.md",
+				LineNumber:    16,
+				Tags:          []string{},
+				ScheduledDate: "2024-02-01",
+			},
+		},
+		{
+			name:       "task with all date fields",
+			line:       "- [x] Ship it #work ➕ 2024-01-01 🛫 2024-01-02 ⏳ 2024-01-03 📅 2024-01-04 ✅ 2024-01-05",
+			filePath:   "todo.md",
+			lineNumber: 17,
+			want: &Task{
+				ID:            "todo.md:17",
+				Description:   "Ship it",
+				Status:        "complete",
+				FilePath:      "todo.md",
+				LineNumber:    17,
+				Tags:          []string{"work"},
+				CreatedDate:   "2024-01-01",
+				StartDate:     "2024-01-02",
+				ScheduledDate: "2024-01-03",
+				DueDate:       "2024-01-04",
+				DoneDate:      "2024-01-05",
+			},
+		},
+		{
+			name:       "task with cancelled date and hourglass variant",
+			line:       "- [ ] Old idea ⌛ 2024-03-01 ❌ 2024-03-02",
+			filePath:   "todo.md",
+			lineNumber: 18,
+			want: &Task{
+				ID:            "todo.md:18",
+				Description:   "Old idea",
+				Status:        "incomplete",
+				FilePath:      "todo.md",
+				LineNumber:    18,
+				Tags:          []string{},
+				ScheduledDate: "2024-03-01",
+				CancelledDate: "2024-03-02",
+			},
+		},
+		{
+			name:       "in-progress task",
+			line:       "- [/] Write report",
+			filePath:   "todo.md",
+			lineNumber: 19,
+			want: &Task{
+				ID:           "todo.md:19",
+				Description:  "Write report",
+				Status:       "incomplete",
+				StatusSymbol: "/",
+				StatusName:   "In Progress",
+				StatusType:   StatusTypeInProgress,
+				FilePath:     "todo.md",
+				LineNumber:   19,
+				Tags:         []string{},
+			},
+		},
+		{
+			name:       "cancelled task counts as complete",
+			line:       "- [-] Abandoned idea",
+			filePath:   "todo.md",
+			lineNumber: 20,
+			want: &Task{
+				ID:           "todo.md:20",
+				Description:  "Abandoned idea",
+				Status:       "complete",
+				StatusSymbol: "-",
+				StatusName:   "Cancelled",
+				StatusType:   StatusTypeCancelled,
+				FilePath:     "todo.md",
+				LineNumber:   20,
+				Tags:         []string{},
+			},
+		},
+		{
+			name:       "upper-case X is done",
+			line:       "- [X] Shouted task",
+			filePath:   "todo.md",
+			lineNumber: 21,
+			want: &Task{
+				ID:           "todo.md:21",
+				Description:  "Shouted task",
+				Status:       "complete",
+				StatusSymbol: "X",
+				StatusName:   "Done",
+				StatusType:   StatusTypeDone,
+				FilePath:     "todo.md",
+				LineNumber:   21,
+				Tags:         []string{},
+			},
+		},
+		{
+			name:       "custom status symbol",
+			line:       "- [?] Ask about budget",
+			filePath:   "todo.md",
+			lineNumber: 22,
+			want: &Task{
+				ID:           "todo.md:22",
+				Description:  "Ask about budget",
+				Status:       "incomplete",
+				StatusSymbol: "?",
+				StatusName:   "Unknown",
+				StatusType:   StatusTypeTodo,
+				FilePath:     "todo.md",
+				LineNumber:   22,
+				Tags:         []string{},
+			},
+		},
+		{
+			name:       "empty checkbox is not a task",
+			line:       "- [] Not a task",
+			filePath:   "todo.md",
+			lineNumber: 23,
+			want:       nil,
+		},
+		{
+			name:       "recurring task",
+			line:       "- [ ] Water plants 🔁 every week on Monday, Friday 📅 2026-10-16 #home",
+			filePath:   "todo.md",
+			lineNumber: 3,
+			want: &Task{
+				ID:          "todo.md:3",
+				Description: "Water plants",
+				Status:      "incomplete",
+				FilePath:    "todo.md",
+				LineNumber:  3,
+				Tags:        []string{"home"},
+				DueDate:     "2026-10-16",
+				Recurrence:  "every week on Monday, Friday",
+			},
+		},
+		{
+			name:       "task with dependencies",
+			line:       "- [ ] Ship it 🆔 ship ⛔ build, test 📅 2026-10-20",
+			filePath:   "todo.md",
+			lineNumber: 4,
+			want: &Task{
+				ID:           "todo.md:4",
+				Description:  "Ship it",
+				Status:       "incomplete",
+				FilePath:     "todo.md",
+				LineNumber:   4,
+				Tags:         []string{},
+				DueDate:      "2026-10-20",
+				DependencyID: "ship",
+				DependsOn:    []string{"build", "test"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -249,11 +421,81 @@ func TestParseTask(t *testing.T) {
 			assert.Equal(t, tt.want.ID, got.ID)
 			assert.Equal(t, tt.want.Description, got.Description)
 			assert.Equal(t, tt.want.Status, got.Status)
+
+			if tt.want.StatusSymbol != "" {
+				assert.Equal(t, tt.want.StatusSymbol, got.StatusSymbol)
+				assert.Equal(t, tt.want.StatusName, got.StatusName)
+				assert.Equal(t, tt.want.StatusType, got.StatusType)
+			}
+
 			assert.Equal(t, tt.want.FilePath, got.FilePath)
 			assert.Equal(t, tt.want.LineNumber, got.LineNumber)
 			assert.Equal(t, tt.want.Tags, got.Tags)
 			assert.Equal(t, tt.want.DueDate, got.DueDate)
+			assert.Equal(t, tt.want.ScheduledDate, got.ScheduledDate)
+			assert.Equal(t, tt.want.StartDate, got.StartDate)
+			assert.Equal(t, tt.want.CreatedDate, got.CreatedDate)
+			assert.Equal(t, tt.want.DoneDate, got.DoneDate)
+			assert.Equal(t, tt.want.CancelledDate, got.CancelledDate)
 			assert.Equal(t, tt.want.Priority, got.Priority)
+			assert.Equal(t, tt.want.Recurrence, got.Recurrence)
+			assert.Equal(t, tt.want.DependencyID, got.DependencyID)
+			assert.Equal(t, tt.want.DependsOn, got.DependsOn)
 		})
 	}
 }
+
+func TestFormatTaskRoundTrip(t *testing.T) {
+	tasks := []*Task{
+		{Description: "Buy groceries", Tags: []string{}},
+		{Description: "Ship it", StatusSymbol: "x", Tags: []string{"work", "release"}, Priority: PriorityHighest,
+			CreatedDate: "2024-01-01", StartDate: "2024-01-02", ScheduledDate: "2024-01-03", DueDate: "2024-01-04",
+			DoneDate: "2024-01-05"},
+		{Description: "Drop it", StatusSymbol: "-", Tags: []string{}, Priority: PriorityLow, CancelledDate: "2024-02-01"},
+		{Description: "Water plants", Tags: []string{"home"}, Recurrence: "every week on Sunday when done", DueDate: "2024-01-07",
+			DependencyID: "water", DependsOn: []string{"buy-can", "fill-can"}},
+	}
+
+	for _, want := range tasks {
+		line := FormatTask(want)
+
+		got := ParseTask(line, "todo.md", 1)
+		require.NotNil(t, got, line)
+		assert.Equal(t, want.Description, got.Description, line)
+		assert.Equal(t, want.Tags, got.Tags, line)
+		assert.Equal(t, want.Priority, got.Priority, line)
+		assert.Equal(t, want.CreatedDate, got.CreatedDate, line)
+		assert.Equal(t, want.StartDate, got.StartDate, line)
+		assert.Equal(t, want.ScheduledDate, got.ScheduledDate, line)
+		assert.Equal(t, want.DueDate, got.DueDate, line)
+		assert.Equal(t, want.DoneDate, got.DoneDate, line)
+		assert.Equal(t, want.CancelledDate, got.CancelledDate, line)
+		assert.Equal(t, want.Recurrence, got.Recurrence, line)
+		assert.Equal(t, want.DependencyID, got.DependencyID, line)
+		assert.Equal(t, want.DependsOn, got.DependsOn, line)
+	}
+
+	assert.Equal(t, "- [ ] Plain", FormatTask(&Task{Description: "Plain"}))
+	assert.Equal(t, "- [x] Ship it #work #release 🔺 ➕ 2024-01-01 🛫 2024-01-02 ⏳ 2024-01-03 📅 2024-01-04 ✅ 2024-01-05",
+		FormatTask(tasks[1]))
+	assert.Equal(t, "- [ ] Water plants #home 🆔 water ⛔ buy-can,fill-can 🔁 every week on Sunday when done 📅 2024-01-07",
+		FormatTask(tasks[3]))
+}
+
+func TestParsePriorityName(t *testing.T) {
+	for name, want := range map[string]Priority{
+		"highest": PriorityHighest,
+		"High":    PriorityHigh,
+		"medium":  PriorityMedium,
+		"low":     PriorityLow,
+		"none":    PriorityNone,
+		"":        PriorityNone,
+	} {
+		got, err := parsePriorityName(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, name)
+	}
+
+	_, err := parsePriorityName("urgent")
+	require.Error(t, err)
+}
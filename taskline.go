@@ -0,0 +1,247 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// signifierRegex matches every metadata signifier the Tasks plugin writes,
+	// including those ParseTask does not interpret. Text from the first
+	// signifier onwards is metadata rather than description.
+	signifierRegex = regexp.MustCompile(`🔺|⏫|🔼|🔽|⏬|🔁|🏁|➕|🛫|⏳|⌛|📅|📆|🗓️|🗓|✅|❌|🆔|⛔`)
+	blockLinkRegex = regexp.MustCompile(`\s\^[A-Za-z0-9-]+$`)
+)
+
+// taskLine is a task line split into the parts that ParseTask reads. Its
+// setters edit only the spans belonging to the field being changed, so that
+// String returns the original line byte-for-byte when nothing is changed, and
+// untouched fields (including metadata ParseTask does not understand) are
+// preserved when something is.
+type taskLine struct {
+	indent  string
+	symbol  string
+	content string
+}
+
+// splitTaskLine splits a task line, returning false if line is not a task
+func splitTaskLine(line string) (*taskLine, bool) {
+	matches := taskRegex.FindStringSubmatch(line)
+	if len(matches) < 4 {
+		return nil, false
+	}
+
+	return &taskLine{indent: matches[1], symbol: matches[2], content: matches[3]}, true
+}
+
+func (l *taskLine) String() string {
+	return l.indent + "- [" + l.symbol + "]" + l.content
+}
+
+// setStatus changes the status symbol, adding a done date when the task
+// becomes done and removing it when the task stops being done
+func (l *taskLine) setStatus(registry *StatusRegistry, symbol, doneDate string) {
+	current := registry.Lookup(l.symbol)
+	next := registry.Lookup(symbol)
+
+	switch {
+	case next.Type == StatusTypeDone && current.Type != StatusTypeDone:
+		l.setDate(doneDateRegex, "✅", doneDate)
+	case next.Type != StatusTypeDone && current.Type == StatusTypeDone:
+		l.setDate(doneDateRegex, "✅", "")
+	}
+
+	l.symbol = symbol
+}
+
+// setDate sets the date field matched by re, replacing it in place if present
+// and adding it where the Tasks plugin would otherwise. An empty date removes
+// the field.
+func (l *taskLine) setDate(re *regexp.Regexp, emoji, date string) {
+	loc := re.FindStringIndex(l.content)
+
+	switch {
+	case loc == nil && date == "":
+		return
+	case loc == nil:
+		l.insertField(emoji, emoji+" "+date)
+	case date == "":
+		l.content = removeSpan(l.content, loc[0], loc[1])
+		// remove any duplicates too, so ParseTask no longer sees the field
+		l.setDate(re, emoji, date)
+	default:
+		l.content = l.content[:loc[0]] + emoji + " " + date + l.content[loc[1]:]
+	}
+}
+
+// setPriority replaces the first priority emoji in place, removing any
+// others, or adds the priority where the Tasks plugin would if the task had
+// none
+func (l *taskLine) setPriority(p Priority) {
+	locs := priorityRegex.FindAllStringIndex(l.content, -1)
+
+	if len(locs) == 0 {
+		if p != PriorityNone {
+			l.insertField(p.Emoji(), p.Emoji())
+		}
+
+		return
+	}
+
+	// work backwards so earlier offsets stay valid
+	for i := len(locs) - 1; i > 0; i-- {
+		l.content = removeSpan(l.content, locs[i][0], locs[i][1])
+	}
+
+	if p == PriorityNone {
+		l.content = removeSpan(l.content, locs[0][0], locs[0][1])
+
+		return
+	}
+
+	l.content = l.content[:locs[0][0]] + p.Emoji() + l.content[locs[0][1]:]
+}
+
+// setTags replaces the task's tags. Tags that are kept stay where they are;
+// removed tags are deleted and new tags are added at the end of the
+// description.
+func (l *taskLine) setTags(tags []string) {
+	locs := tagRegex.FindAllStringIndex(l.content, -1)
+
+	have := make([]string, 0, len(locs))
+
+	for i := len(locs) - 1; i >= 0; i-- {
+		tag := strings.TrimPrefix(l.content[locs[i][0]:locs[i][1]], "#")
+		if slices.Contains(tags, tag) && !slices.Contains(have, tag) {
+			have = append(have, tag)
+
+			continue
+		}
+
+		l.content = removeSpan(l.content, locs[i][0], locs[i][1])
+	}
+
+	var added []string
+
+	for _, tag := range tags {
+		if !slices.Contains(have, tag) && !slices.Contains(added, "#"+tag) {
+			added = append(added, "#"+tag)
+		}
+	}
+
+	if len(added) > 0 {
+		l.insertAfterDescription(strings.Join(added, " "))
+	}
+}
+
+// setDescription replaces the description text, keeping any tags within it
+// and all metadata after it
+func (l *taskLine) setDescription(description string) {
+	end := l.descriptionEnd()
+	tags := tagRegex.FindAllString(l.content[:end], -1)
+
+	parts := append([]string{description}, tags...)
+	rest := strings.TrimLeft(l.content[end:], " \t")
+
+	l.content = " " + strings.Join(parts, " ")
+	if rest != "" {
+		l.content += " " + rest
+	}
+}
+
+// descriptionEnd returns the offset in content where the description ends:
+// the first metadata signifier, or the trailing block link
+func (l *taskLine) descriptionEnd() int {
+	if loc := signifierRegex.FindStringIndex(l.content); loc != nil {
+		return loc[0]
+	}
+
+	trimmed := strings.TrimRight(l.content, " \t")
+	if loc := blockLinkRegex.FindStringIndex(trimmed); loc != nil {
+		return loc[0]
+	}
+
+	return len(trimmed)
+}
+
+// insertAfterDescription inserts text between the description and the
+// metadata that follows it
+func (l *taskLine) insertAfterDescription(text string) {
+	end := l.descriptionEnd()
+	head := strings.TrimRight(l.content[:end], " \t")
+	tail := l.content[end:]
+
+	if tail != "" && !strings.HasPrefix(tail, " ") && !strings.HasPrefix(tail, "\t") {
+		tail = " " + tail
+	}
+
+	l.content = head + " " + text + tail
+}
+
+// fieldSignifiers lists the metadata signifiers in the order the Tasks plugin
+// writes fields (as FormatTask does), with each field's alternatives together
+func fieldSignifiers() [][]string {
+	return [][]string{
+		{"🆔"},
+		{"⛔"},
+		{"🔺", "⏫", "🔼", "🔽", "⏬"},
+		{"🔁"},
+		{"🏁"},
+		{"➕"},
+		{"🛫"},
+		{"⏳", "⌛"},
+		{"📅", "📆", "🗓️", "🗓"},
+		{"❌"},
+		{"✅"},
+	}
+}
+
+// insertField adds a metadata field, whose signifier is emoji, before the
+// first field that the Tasks plugin writes after it, or at the end (before
+// any block link) if there is none
+func (l *taskLine) insertField(emoji, field string) {
+	groups := fieldSignifiers()
+
+	later := slices.IndexFunc(groups, func(group []string) bool { return slices.Contains(group, emoji) }) + 1
+	if later == 0 {
+		later = len(groups)
+	}
+
+	end := l.descriptionEnd()
+	pos := -1
+
+	for _, group := range groups[later:] {
+		for _, signifier := range group {
+			if i := strings.Index(l.content[end:], signifier); i >= 0 && (pos < 0 || end+i < pos) {
+				pos = end + i
+			}
+		}
+	}
+
+	if pos < 0 {
+		l.content = appendField(l.content, field)
+
+		return
+	}
+
+	l.content = l.content[:pos] + field + " " + l.content[pos:]
+}
+
+// appendField appends a metadata field to a task's content, keeping any
+// trailing block link (^id) and whitespace at the end
+func appendField(content, field string) string {
+	trimmed := strings.TrimRight(content, " \t")
+	trailing := content[len(trimmed):]
+
+	if loc := blockLinkRegex.FindStringIndex(trimmed); loc != nil {
+		return trimmed[:loc[0]] + " " + field + trimmed[loc[0]:] + trailing
+	}
+
+	return trimmed + " " + field + trailing
+}
+
+// removeSpan removes content[start:end] together with the whitespace before it
+func removeSpan(content string, start, end int) string {
+	return strings.TrimRight(content[:start], " \t") + content[end:]
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskLineUnchanged(t *testing.T) {
+	for _, line := range []string{
+		"- [ ] Buy milk",
+		"    - [/] Spaced   out 🔁 every week #tag ⏫ 📅 2026-10-20  ",
+		"- [x] Done ✅ 2026-10-01 ^block-1",
+	} {
+		l, ok := splitTaskLine(line)
+		require.True(t, ok)
+		assert.Equal(t, line, l.String())
+	}
+
+	_, ok := splitTaskLine("- plain list item")
+	assert.False(t, ok)
+}
+
+//nolint:funlen // comprehensive test cases
+func TestTaskLineSetters(t *testing.T) {
+	tests := []struct {
+		edit func(l *taskLine)
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "replace due date in place",
+			line: "- [ ] Pay rent 📅 2026-10-01 ⛔ abc123",
+			edit: func(l *taskLine) { l.setDate(dueDateRegex, "📅", "2026-11-01") },
+			want: "- [ ] Pay rent 📅 2026-11-01 ⛔ abc123",
+		},
+		{
+			name: "add due date before block link",
+			line: "- [ ] Pay rent ^rent",
+			edit: func(l *taskLine) { l.setDate(dueDateRegex, "📅", "2026-11-01") },
+			want: "- [ ] Pay rent 📅 2026-11-01 ^rent",
+		},
+		{
+			name: "add due date before cancelled and done dates",
+			line: "- [x] Pay rent 🔁 every month ❌ 2026-10-02 ✅ 2026-10-03",
+			edit: func(l *taskLine) { l.setDate(dueDateRegex, "📅", "2026-11-01") },
+			want: "- [x] Pay rent 🔁 every month 📅 2026-11-01 ❌ 2026-10-02 ✅ 2026-10-03",
+		},
+		{
+			name: "add start date before the dates written after it",
+			line: "- [x] Pay rent ➕ 2026-09-01 📅 2026-10-01 ✅ 2026-10-03 ^rent",
+			edit: func(l *taskLine) { l.setDate(startDateRegex, "🛫", "2026-09-15") },
+			want: "- [x] Pay rent ➕ 2026-09-01 🛫 2026-09-15 📅 2026-10-01 ✅ 2026-10-03 ^rent",
+		},
+		{
+			name: "add done date after the other fields",
+			line: "- [ ] Pay rent 📅 2026-10-01 ^rent",
+			edit: func(l *taskLine) { l.setDate(doneDateRegex, "✅", "2026-10-03") },
+			want: "- [ ] Pay rent 📅 2026-10-01 ✅ 2026-10-03 ^rent",
+		},
+		{
+			name: "remove due date",
+			line: "- [ ] Pay rent 📅 2026-10-01 🔁 every month",
+			edit: func(l *taskLine) { l.setDate(dueDateRegex, "📅", "") },
+			want: "- [ ] Pay rent 🔁 every month",
+		},
+		{
+			name: "change priority in place",
+			line: "- [ ] Pay rent 🔼 📅 2026-10-01",
+			edit: func(l *taskLine) { l.setPriority(PriorityHighest) },
+			want: "- [ ] Pay rent 🔺 📅 2026-10-01",
+		},
+		{
+			name: "add priority",
+			line: "- [ ] Pay rent",
+			edit: func(l *taskLine) { l.setPriority(PriorityLow) },
+			want: "- [ ] Pay rent 🔽",
+		},
+		{
+			name: "add priority before dates",
+			line: "- [ ] Pay rent #bills ⛔ abc123 📅 2026-10-01 ✅ 2026-10-03",
+			edit: func(l *taskLine) { l.setPriority(PriorityHigh) },
+			want: "- [ ] Pay rent #bills ⛔ abc123 ⏫ 📅 2026-10-01 ✅ 2026-10-03",
+		},
+		{
+			name: "remove priority",
+			line: "- [ ] Pay rent ⏫ 📅 2026-10-01",
+			edit: func(l *taskLine) { l.setPriority(PriorityNone) },
+			want: "- [ ] Pay rent 📅 2026-10-01",
+		},
+		{
+			name: "replace tags keeping existing positions",
+			line: "- [ ] Call #work Bob #phone 📅 2026-10-01",
+			edit: func(l *taskLine) { l.setTags([]string{"work", "urgent"}) },
+			want: "- [ ] Call #work Bob #urgent 📅 2026-10-01",
+		},
+		{
+			name: "clear tags",
+			line: "- [ ] Call #work Bob #phone",
+			edit: func(l *taskLine) { l.setTags(nil) },
+			want: "- [ ] Call Bob",
+		},
+		{
+			name: "change description keeping tags and unknown metadata",
+			line: "  - [ ] Call Bob #phone 🔁 every week 📅 2026-10-01",
+			edit: func(l *taskLine) { l.setDescription("Email Alice") },
+			want: "  - [ ] Email Alice #phone 🔁 every week 📅 2026-10-01",
+		},
+		{
+			name: "change description keeping block link",
+			line: "- [ ] Call Bob ^call",
+			edit: func(l *taskLine) { l.setDescription("Email Alice") },
+			want: "- [ ] Email Alice ^call",
+		},
+		{
+			name: "complete task",
+			line: "- [ ] Call Bob 📅 2026-10-01",
+			edit: func(l *taskLine) { l.setStatus(nil, "x", "2026-10-16") },
+			want: "- [x] Call Bob 📅 2026-10-01 ✅ 2026-10-16",
+		},
+		{
+			name: "reopen task",
+			line: "- [x] Call Bob ✅ 2026-10-16 📅 2026-10-01",
+			edit: func(l *taskLine) { l.setStatus(nil, "/", "2026-10-17") },
+			want: "- [/] Call Bob 📅 2026-10-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, ok := splitTaskLine(tt.line)
+			require.True(t, ok)
+
+			tt.edit(l)
+			assert.Equal(t, tt.want, l.String())
+		})
+	}
+}
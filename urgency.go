@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Coefficients of the Tasks plugin's urgency score
+const (
+	urgencyDueCoefficient       = 12.0
+	urgencyScheduledCoefficient = 5.0
+	urgencyStartedCoefficient   = -3.0
+)
+
+// urgency computes a task's urgency score on day as the Tasks plugin does,
+// from its due, scheduled and start dates and its priority. The result is
+// rounded to two decimal places.
+func urgency(task *Task, day time.Time) float64 {
+	var score float64
+
+	if days, ok := daysUntil(task.DueDate, day); ok {
+		overdue := float64(-days)
+
+		multiplier := 0.2
+
+		switch {
+		case overdue >= 7:
+			multiplier = 1
+		case overdue >= -14:
+			multiplier = ((overdue+14)*0.8)/21 + 0.2
+		}
+
+		score += multiplier * urgencyDueCoefficient
+	}
+
+	if days, ok := daysUntil(task.ScheduledDate, day); ok && days <= 0 {
+		score += urgencyScheduledCoefficient
+	}
+
+	if days, ok := daysUntil(task.StartDate, day); ok && days > 0 {
+		score += urgencyStartedCoefficient
+	}
+
+	switch task.Priority {
+	case PriorityHighest:
+		score += 9
+	case PriorityHigh:
+		score += 6
+	case PriorityMedium:
+		score += 3.9
+	case PriorityNone:
+		score += 1.95
+	case PriorityLow:
+	}
+
+	return math.Round(score*100) / 100
+}
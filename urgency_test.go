@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUrgency(t *testing.T) {
+	day := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		line string
+		want float64
+	}{
+		{name: "no dates or priority", line: "- [ ] Task", want: 1.95},
+		{name: "due today", line: "- [ ] Task 📅 2026-10-16", want: 10.75},
+		{name: "due yesterday, highest", line: "- [ ] Task 🔺 📅 2026-10-15", want: 18.26},
+		{name: "a week overdue", line: "- [ ] Task 📅 2026-10-09", want: 13.95},
+		{name: "due in two weeks", line: "- [ ] Task 📅 2026-10-30", want: 4.35},
+		{name: "due far in the future", line: "- [ ] Task 📅 2027-01-01", want: 4.35},
+		{name: "scheduled today, high", line: "- [ ] Task ⏫ ⏳ 2026-10-16", want: 11},
+		{name: "scheduled in the future, medium", line: "- [ ] Task 🔼 ⏳ 2026-10-17", want: 3.9},
+		{name: "starts tomorrow, low", line: "- [ ] Task 🔽 🛫 2026-10-17", want: -3},
+		{name: "started already", line: "- [ ] Task 🛫 2026-10-16", want: 1.95},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, urgency(ParseTask(tt.line, "todo.md", 1), day), 0.0001)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// WarningKind classifies a problem that kept a scan from reading part of the
+// vault
+type WarningKind string
+
+const (
+	// WarningUnreadableFile is a markdown file that couldn't be read, so
+	// none of its tasks are included
+	WarningUnreadableFile WarningKind = "unreadable_file"
+	// WarningLineTooLong is a line longer than maxLineLength, which is
+	// skipped
+	WarningLineTooLong WarningKind = "line_too_long"
+	// WarningUnreadableDirectory is a folder that couldn't be listed (e.g.
+	// permission denied), so the files in it are missing
+	WarningUnreadableDirectory WarningKind = "unreadable_directory"
+)
+
+// ScanWarning reports a file or folder a scan couldn't fully read. The scan
+// carries on without it, so the results may be incomplete.
+type ScanWarning struct {
+	// File is the path of the file or folder, relative to the scanned
+	// directory
+	File    string      `json:"file"`
+	Kind    WarningKind `json:"kind"`
+	Message string      `json:"message"`
+}
+
+// newScanWarning returns a warning about path, inside rootDir, caused by err.
+// The path is left out of the message, since the warning names it already.
+func newScanWarning(rootDir, path string, kind WarningKind, err error) ScanWarning {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+
+	file := path
+	if relPath, relErr := filepath.Rel(rootDir, path); relErr == nil {
+		file = relPath
+	}
+
+	return ScanWarning{File: file, Kind: kind, Message: err.Error()}
+}
+
+// sortWarnings orders warnings by file. Warnings for the same file keep their
+// order, e.g. by line.
+func sortWarnings(warnings []ScanWarning) {
+	slices.SortStableFunc(warnings, func(a, b ScanWarning) int {
+		return cmp.Compare(a.File, b.File)
+	})
+}
@@ -0,0 +1,219 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotifyWatcher maps inotify watch descriptors to the folders they watch
+type inotifyWatcher struct {
+	dirs map[int32]string
+	// vaults maps the watches on settings folders (.obsidian and the Tasks
+	// plugin's folder) to their vault, whose settings reload when they
+	// change
+	vaults map[int32]string
+	fd     int
+}
+
+// watchEvents populates the index and keeps it current from inotify events
+// until ctx is done. It returns an error if inotify can't be set up (e.g.
+// when the per-user watch limit is reached) or stops working.
+func (ix *TaskIndex) watchEvents(ctx context.Context) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("inotify: %w", err)
+	}
+
+	// a non-blocking file is read through the runtime poller, so closing it
+	// interrupts a pending read
+	file := os.NewFile(uintptr(fd), "inotify")
+	defer file.Close()
+
+	w := &inotifyWatcher{fd: fd, dirs: make(map[int32]string), vaults: make(map[int32]string)}
+
+	for _, root := range ix.roots {
+		if err := w.addTree(ix.watchRules(root), root.dir); err != nil {
+			return err
+		}
+	}
+
+	// watch before the first refresh, so no change is missed. Queries read
+	// the files until the index is complete, so errors are not fatal.
+	logIndexError(ix.Refresh(ctx))
+
+	stop := context.AfterFunc(ctx, func() { file.Close() })
+	defer stop()
+
+	buf := make([]byte, 64*1024)
+
+	for {
+		n, err := file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("inotify: %w", err)
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			if err := w.handle(ctx, ix, event, name); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle applies a single inotify event to the index
+func (w *inotifyWatcher) handle(ctx context.Context, ix *TaskIndex, event *syscall.InotifyEvent, name string) error {
+	switch {
+	case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+		// events were lost, so check everything
+		logIndexError(ix.Refresh(ctx))
+
+		return nil
+	case event.Mask&syscall.IN_IGNORED != 0:
+		delete(w.dirs, event.Wd)
+		delete(w.vaults, event.Wd)
+
+		return nil
+	}
+
+	dir, ok := w.dirs[event.Wd]
+	if !ok {
+		return nil
+	}
+
+	path := filepath.Join(dir, name)
+
+	if vaultDir, ok := w.vaults[event.Wd]; ok {
+		return w.handleConfig(ctx, ix, vaultDir, name)
+	}
+
+	if name == ".obsidian" && event.Mask&syscall.IN_ISDIR != 0 {
+		// the folder became (or stopped being) a vault, whose settings
+		// folders weren't there to watch before
+		return w.handleConfig(ctx, ix, dir, name)
+	}
+
+	if root := ix.rootOf(path); root != nil && event.Mask&syscall.IN_ISDIR != 0 &&
+		event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+		if err := w.addTree(ix.watchRules(root), path); err != nil {
+			return err
+		}
+	}
+
+	ix.RefreshFile(path)
+
+	return nil
+}
+
+// handleConfig applies a change to name in the settings folders of the vault
+// at vaultDir, watching any settings folders that were created
+func (w *inotifyWatcher) handleConfig(ctx context.Context, ix *TaskIndex, vaultDir, name string) error {
+	// Obsidian rewrites files such as workspace.json all the time, so only
+	// react to the settings files (and folders) we read
+	if !slices.Contains([]string{".obsidian", "app.json", "data.json", "plugins", "obsidian-tasks-plugin"}, name) {
+		return nil
+	}
+
+	if err := w.addConfigDir(vaultDir); err != nil {
+		return err
+	}
+
+	// Refresh reloads the settings, re-parsing the vault's files if they
+	// changed
+	logIndexError(ix.Refresh(ctx))
+
+	return nil
+}
+
+// addTree watches dir and every folder inside it that a scan would visit,
+// plus the settings folders of the vault containing dir. A symlinked dir is
+// followed, and its folders are watched under the path as spelled.
+func (w *inotifyWatcher) addTree(rules *excludeRules, dir string) error {
+	if configDir, ok := findConfigDir(dir); ok {
+		if err := w.addConfigDir(filepath.Dir(configDir)); err != nil {
+			return err
+		}
+	}
+
+	walkDir, unresolve := followDir(dir)
+
+	return filepath.WalkDir(walkDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil //nolint:nilerr // folders that vanish are skipped
+		}
+
+		path = unresolve(path)
+
+		if rules.skip(path, true) {
+			return filepath.SkipDir
+		}
+
+		return w.add(path, "")
+	})
+}
+
+// addConfigDir watches the .obsidian folder of the vault at vaultDir and the
+// folders down to the Tasks plugin's, where the settings files we read live
+func (w *inotifyWatcher) addConfigDir(vaultDir string) error {
+	configDir := filepath.Join(vaultDir, ".obsidian")
+
+	pluginDir := filepath.Dir(filepath.Join(configDir, tasksPluginSettingsPath))
+
+	for _, dir := range []string{configDir, filepath.Dir(pluginDir), pluginDir} {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		if err := w.add(dir, vaultDir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// watchRules returns the exclude rules deciding which folders of root to
+// watch. If the vault's excluded files setting is invalid, folders are
+// watched regardless, so that the index catches up once it is fixed.
+func (ix *TaskIndex) watchRules(root *indexRoot) *excludeRules {
+	rules, err := newExcludeRules(root.dir, ix.exclude)
+	if err != nil {
+		return &excludeRules{root: root.dir, globs: ix.exclude}
+	}
+
+	return rules
+}
+
+// add watches dir. vaultDir is set for the settings folders of that vault.
+func (w *inotifyWatcher) add(dir, vaultDir string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return fmt.Errorf("inotify: watching %q: %w", dir, err)
+	}
+
+	w.dirs[int32(wd)] = dir //nolint:gosec // watch descriptors are small
+	if vaultDir != "" {
+		w.vaults[int32(wd)] = vaultDir //nolint:gosec // watch descriptors are small
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskIndexWatch(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] One\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(vault, ".obsidian"), 0o755))
+
+	ix, err := NewTaskIndex([]string{vault}, nil, 0)
+	require.NoError(t, err)
+
+	// poll so rarely that only inotify can keep the index current
+	go ix.Watch(t.Context(), time.Hour)
+
+	eventually := func(want ...string) {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			return slices.Equal(want, indexedDescriptions(t, ix, []string{vault}))
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	eventually("todo.md: One")
+
+	writeNote(t, vault, "todo.md", "- [ ] One\n- [ ] #task Two\n")
+	eventually("todo.md: One", "todo.md: Two")
+
+	// new folders are watched too
+	writeNote(t, vault, "Projects/2026/plan.md", "- [ ] Three\n")
+	eventually("Projects/2026/plan.md: Three", "todo.md: One", "todo.md: Two")
+
+	writeNote(t, vault, "Projects/2026/plan.md", "- [ ] Three, edited\n")
+	eventually("Projects/2026/plan.md: Three, edited", "todo.md: One", "todo.md: Two")
+
+	require.NoError(t, os.Rename(filepath.Join(vault, "Projects"), filepath.Join(vault, ".trash")))
+	eventually("todo.md: One", "todo.md: Two")
+
+	// installing the Tasks plugin with a global filter re-parses the vault
+	writeTasksSettings(t, vault, `{"globalFilter": "#task"}`)
+	eventually("todo.md: Two")
+}
+
+func TestTaskIndexWatchSymlinkedRoot(t *testing.T) {
+	base := t.TempDir()
+	vault := filepath.Join(base, "vault")
+	link := filepath.Join(base, "link")
+
+	writeNote(t, vault, "todo.md", "- [ ] One\n")
+	require.NoError(t, os.Symlink(vault, link))
+
+	ix, err := NewTaskIndex([]string{link}, nil, 0)
+	require.NoError(t, err)
+
+	go ix.Watch(t.Context(), time.Hour)
+
+	eventually := func(want ...string) {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			return slices.Equal(want, indexedDescriptions(t, ix, []string{link}))
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	eventually("todo.md: One")
+
+	writeNote(t, vault, "Projects/plan.md", "- [ ] Two\n")
+	eventually("Projects/plan.md: Two", "todo.md: One")
+
+	writeNote(t, vault, "Projects/plan.md", "- [ ] Two, edited\n")
+	eventually("Projects/plan.md: Two, edited", "todo.md: One")
+}
+
+func TestTaskIndexWatchNewVault(t *testing.T) {
+	vault := t.TempDir()
+	writeNote(t, vault, "todo.md", "- [ ] One\n- [ ] #task Two\n")
+
+	ix, err := NewTaskIndex([]string{vault}, nil, 0)
+	require.NoError(t, err)
+
+	go ix.Watch(t.Context(), time.Hour)
+
+	eventually := func(want ...string) {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			return slices.Equal(want, indexedDescriptions(t, ix, []string{vault}))
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	eventually("todo.md: One", "todo.md: Two")
+
+	// the settings folders are watched once they are created
+	require.NoError(t, os.Mkdir(filepath.Join(vault, ".obsidian"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(vault, ".obsidian", "plugins"), 0o755))
+	writeTasksSettings(t, vault, `{"globalFilter": "#task"}`)
+	eventually("todo.md: Two")
+
+	writeTasksSettings(t, vault, `{"globalFilter": ""}`)
+	eventually("todo.md: One", "todo.md: Two")
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// watchEvents is only implemented on Linux; elsewhere the index is polled
+func (ix *TaskIndex) watchEvents(context.Context) error {
+	return errors.New("file watching is not supported on this platform")
+}
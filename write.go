@@ -0,0 +1,851 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// errTaskChanged is returned when a task line no longer matches what the
+// client read, so an edit would clobber someone else's change
+var errTaskChanged = errors.New("task has changed since it was read")
+
+// errDescriptionRequired is returned by the edit tools when the client
+// doesn't say which task it expects to find on the line. Lines shift as notes
+// are edited, so an id alone could point at a different task.
+var errDescriptionRequired = errors.New("the task's current description is required, to make sure the id still points at the same task")
+
+var (
+	headingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*$`)
+	tagNameRegex = regexp.MustCompile(`^` + tagPattern + `$`)
+)
+
+type ToggleTaskInput struct {
+	ID string `json:"id" jsonschema:"Task id as returned by query_tasks, in the form /path/to/file.md:lineNumber"`
+
+	Description string `json:"description" jsonschema:"Task description as returned by query_tasks. The edit is refused unless the task on that line still has this description"`
+}
+
+type ToggleTaskOutput struct {
+	Task *Task `json:"task"`
+	// NextOccurrence is the new task inserted above a completed recurring task
+	NextOccurrence *Task `json:"nextOccurrence,omitempty"`
+	// Warnings lists what the edit couldn't do, such as creating the next
+	// occurrence of a task whose recurrence rule isn't supported
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (s *taskServer) toggleTask(_ context.Context, _ *mcp.CallToolRequest, input ToggleTaskInput) (
+	*mcp.CallToolResult,
+	ToggleTaskOutput,
+	error,
+) {
+	edited, err := s.editTaskLine("toggle", input.ID, input.Description,
+		func(line string, day time.Time, settings *VaultSettings) (string, error) {
+			return toggleTaskLine(line, settings.doneDate(day.Format(dateLayout)), settings.statuses()), nil
+		})
+	if err != nil {
+		return errorResult(err.Error()), ToggleTaskOutput{}, nil
+	}
+
+	return nil, ToggleTaskOutput{Task: edited.task, NextOccurrence: edited.next, Warnings: edited.warnings}, nil
+}
+
+// editedTask is the outcome of editTaskLine
+type editedTask struct {
+	task *Task
+	// next is the next occurrence of a recurring task the edit completed
+	next     *Task
+	warnings []string
+}
+
+// lineEdit returns the replacement for a task line, given today's date and
+// the vault's settings
+type lineEdit func(line string, day time.Time, settings *VaultSettings) (string, error)
+
+// editTaskLine applies edit to the task with the given id, once it has
+// checked that the line still holds a task with the expected description. A
+// recurring task that the edit completes gets its next occurrence inserted
+// above it. Errors read "failed to <action> task <id>: ...".
+func (s *taskServer) editTaskLine(action, id, description string, edit lineEdit) (*editedTask, error) {
+	edited, err := s.applyLineEdit(id, description, edit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s task %s: %w", action, id, err)
+	}
+
+	return edited, nil
+}
+
+// applyLineEdit does the work of editTaskLine, leaving the errors unwrapped
+func (s *taskServer) applyLineEdit(id, description string, edit lineEdit) (*editedTask, error) {
+	relPath, lineNumber, err := parseTaskID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if description == "" {
+		return nil, errDescriptionRequired
+	}
+
+	path, root, err := s.resolveVaultFile(relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := LoadVaultSettings(root)
+	if err != nil {
+		return nil, err
+	}
+
+	day := today(s.queryOptions.Now, s.queryOptions.Location)
+
+	var (
+		updated  []string
+		warnings []string
+	)
+
+	err = rewriteLine(path, lineNumber, func(line string) ([]string, error) {
+		if err := checkTaskLine(line, description, settings); err != nil {
+			return nil, err
+		}
+
+		edited, err := edit(line, day, settings)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, warnings = withNextOccurrence(line, edited, day, settings)
+
+		return updated, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// don't wait for the watcher, so that the next query sees the change
+	s.index.RefreshFile(path)
+
+	task, next := parseRewrittenTasks(updated, path, root, lineNumber, settings)
+
+	return &editedTask{task: task, next: next, warnings: warnings}, nil
+}
+
+type CreateTaskInput struct {
+	Description string `json:"description" jsonschema:"Task description, without tags or emoji metadata"`
+
+	Path    string `json:"path,omitempty" jsonschema:"Markdown file to add the task to, relative to the vault root. Created if it does not exist"`
+	Daily   bool   `json:"daily,omitempty" jsonschema:"Add the task to today's daily note instead of path"`
+	Heading string `json:"heading,omitempty" jsonschema:"Add the task at the end of the section under this heading, which is created if missing"`
+	Root    string `json:"root,omitempty" jsonschema:"Vault root to write to when several -root directories are configured. Defaults to the first"`
+
+	DueDate       string `json:"dueDate,omitempty" jsonschema:"Due date: YYYY-MM-DD or a relative date such as tomorrow or next friday"`
+	ScheduledDate string `json:"scheduledDate,omitempty" jsonschema:"Scheduled date: YYYY-MM-DD or a relative date"`
+	StartDate     string `json:"startDate,omitempty" jsonschema:"Start date: YYYY-MM-DD or a relative date"`
+
+	Priority string   `json:"priority,omitempty" jsonschema:"Priority: highest, high, medium, low or none"`
+	Tags     []string `json:"tags,omitempty" jsonschema:"Tags to add, with or without the leading #"`
+}
+
+type CreateTaskOutput struct {
+	Task *Task `json:"task"`
+	// Warnings describes anything that didn't go as asked, such as a daily
+	// note format that couldn't be used
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (s *taskServer) createTask(_ context.Context, _ *mcp.CallToolRequest, input CreateTaskInput) (
+	*mcp.CallToolResult,
+	CreateTaskOutput,
+	error,
+) {
+	day := today(s.queryOptions.Now, s.queryOptions.Location)
+
+	task, err := newTaskFromInput(input, day)
+	if err != nil {
+		return errorResult("invalid task: " + err.Error()), CreateTaskOutput{}, nil
+	}
+
+	root, err := s.vaultRoot(input.Root)
+	if err != nil {
+		return errorResult(err.Error()), CreateTaskOutput{}, nil
+	}
+
+	settings, err := LoadVaultSettings(root)
+	if err != nil {
+		return errorResult(err.Error()), CreateTaskOutput{}, nil
+	}
+
+	// follow the vault's Tasks plugin settings, so that the new task is
+	// recognized and looks like one created in Obsidian
+	task.Description = settings.withGlobalFilter(task.Description)
+	if settings != nil && settings.SetCreatedDate {
+		task.CreatedDate = day.Format(dateLayout)
+	}
+
+	var warnings []string
+
+	relPath := input.Path
+	if input.Daily {
+		var warning string
+		if relPath, warning = dailyNotePath(root, day); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	path, err := notePath(root, relPath)
+	if err != nil {
+		return errorResult(err.Error()), CreateTaskOutput{}, nil
+	}
+
+	line := FormatTask(task)
+
+	lineNumber, err := insertIntoFile(path, input.Heading, line)
+	if err != nil {
+		return errorResult("failed to create task: " + err.Error()), CreateTaskOutput{}, nil
+	}
+
+	s.index.RefreshFile(path)
+
+	task = parseTaskAt(line, path, root, lineNumber, settings)
+
+	return nil, CreateTaskOutput{Task: task, Warnings: warnings}, nil
+}
+
+// newTaskFromInput validates the fields of a create_task request
+func newTaskFromInput(input CreateTaskInput, day time.Time) (*Task, error) {
+	description := strings.TrimSpace(input.Description)
+	if description == "" {
+		return nil, errors.New("description is required")
+	}
+
+	if strings.ContainsAny(description, "\r\n") {
+		return nil, errors.New("description must be a single line")
+	}
+
+	priority, err := parsePriorityName(input.Priority)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &Task{Description: description, StatusSymbol: " ", Priority: priority, Tags: tags}
+
+	for _, field := range []struct {
+		dest *string
+		name string
+		expr string
+	}{
+		{&task.DueDate, "due date", input.DueDate},
+		{&task.ScheduledDate, "scheduled date", input.ScheduledDate},
+		{&task.StartDate, "start date", input.StartDate},
+	} {
+		if field.expr == "" {
+			continue
+		}
+
+		date, err := resolveDate(field.expr, day)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", field.name, err)
+		}
+
+		*field.dest = date
+	}
+
+	return task, nil
+}
+
+// resolveDate resolves a date expression that must name a single day
+func resolveDate(expr string, day time.Time) (string, error) {
+	r, err := parseDateRange(expr, day)
+	if err != nil {
+		return "", err
+	}
+
+	if r.Start != r.End {
+		return "", fmt.Errorf("%q is a range of dates, not a single day", expr)
+	}
+
+	return r.Start, nil
+}
+
+// vaultRoot returns the absolute path of the configured root with the given
+// name, or of the first configured root if name is empty
+func (s *taskServer) vaultRoot(name string) (string, error) {
+	for _, root := range s.roots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return "", fmt.Errorf("failed to get absolute path for %q: %w", root, err)
+		}
+
+		if name == "" || name == root || name == absRoot {
+			return absRoot, nil
+		}
+	}
+
+	if name == "" {
+		return "", errors.New("no root directories are configured")
+	}
+
+	return "", fmt.Errorf("%q is not a configured root", name)
+}
+
+// notePath resolves a markdown file path relative to root, refusing paths
+// that escape it
+func notePath(root, relPath string) (string, error) {
+	if relPath == "" {
+		return "", errors.New("either path or daily is required")
+	}
+
+	if filepath.IsAbs(relPath) {
+		return "", fmt.Errorf("path %q must be relative to the vault root", relPath)
+	}
+
+	if !strings.HasSuffix(strings.ToLower(relPath), ".md") {
+		relPath += ".md"
+	}
+
+	path := filepath.Join(root, relPath)
+	if !isWithin(root, path) {
+		return "", fmt.Errorf("path %q is outside the vault root", relPath)
+	}
+
+	// refuse paths through symlinks to folders (or files) outside the root.
+	// The file and its folders may not exist yet, so check the deepest part
+	// of the path that does.
+	resolvedRoot, err := realPath(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve vault root %q: %w", root, err)
+	}
+
+	existing := path
+	for _, err := os.Lstat(existing); errors.Is(err, os.ErrNotExist); _, err = os.Lstat(existing) {
+		existing = filepath.Dir(existing)
+	}
+
+	resolved, err := realPath(existing)
+	if err != nil || !isWithin(resolvedRoot, resolved) {
+		return "", fmt.Errorf("path %q is outside the vault root", relPath)
+	}
+
+	return path, nil
+}
+
+// insertIntoFile adds line to the markdown file at path, under heading if
+// given, creating the file and its parent directories if needed. It returns
+// the (1-based) line number of the inserted line.
+func insertIntoFile(path, heading, line string) (int, error) {
+	before, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		content, lineNumber := insertTaskLine("", heading, line)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return 0, err
+		}
+
+		//nolint:gosec // notes should be readable like any other vault file
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := f.WriteString(content); err != nil {
+			f.Close()
+
+			return 0, err
+		}
+
+		return lineNumber, f.Close()
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	content, lineNumber := insertTaskLine(string(data), heading, line)
+
+	return lineNumber, writeFileIfUnchanged(path, before, []byte(content))
+}
+
+// insertTaskLine inserts line into content: after the last non-blank line of
+// the section under heading, or at the end of the content if heading is empty.
+// A missing heading is appended as a level-2 heading. Existing line endings are
+// preserved and reused for the new line.
+func insertTaskLine(content, heading, line string) (string, int) {
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	// make sure the last line is terminated before appending after it
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += eol
+	}
+
+	idx := len(lines)
+	newLines := []string{line + eol}
+
+	if heading != "" {
+		if at, ok := sectionEnd(lines, heading); ok {
+			idx = at
+		} else {
+			newLines = []string{"## " + heading + eol, line + eol}
+			if idx > 0 && strings.TrimSpace(lines[idx-1]) != "" {
+				newLines = append([]string{eol}, newLines...)
+			}
+		}
+	}
+
+	out := make([]string, 0, len(lines)+len(newLines))
+	out = append(out, lines[:idx]...)
+	out = append(out, newLines...)
+	out = append(out, lines[idx:]...)
+
+	return strings.Join(out, ""), idx + len(newLines)
+}
+
+// sectionEnd finds the heading with the given text, outside code blocks and
+// comments, and returns the index just
+// past the last non-blank line of its section
+func sectionEnd(lines []string, heading string) (int, bool) {
+	start, level := -1, 0
+
+	var md markdownLines
+
+	for i, l := range lines {
+		l = strings.TrimRight(l, "\r\n")
+		if !md.next(l) {
+			continue
+		}
+
+		m := headingRegex.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+
+		if start < 0 {
+			if m[2] == strings.TrimSpace(heading) {
+				start, level = i, len(m[1])
+			}
+
+			continue
+		}
+
+		if len(m[1]) <= level {
+			return lastNonBlank(lines, start, i), true
+		}
+	}
+
+	if start < 0 {
+		return 0, false
+	}
+
+	return lastNonBlank(lines, start, len(lines)), true
+}
+
+// lastNonBlank returns the index just past the last non-blank line in
+// lines[start:end]; start itself is the heading so is never blank
+func lastNonBlank(lines []string, start, end int) int {
+	for i := end - 1; i > start; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i + 1
+		}
+	}
+
+	return start + 1
+}
+
+type UpdateTaskInput struct {
+	ID string `json:"id" jsonschema:"Task id as returned by query_tasks, in the form /path/to/file.md:lineNumber"`
+
+	ExpectedDescription string `json:"expectedDescription" jsonschema:"Task description as returned by query_tasks. The edit is refused unless the task on that line still has this description"`
+
+	Description   *string   `json:"description,omitempty" jsonschema:"New description, without tags or emoji metadata"`
+	DueDate       *string   `json:"dueDate,omitempty" jsonschema:"New due date: YYYY-MM-DD, a relative date such as tomorrow, or empty to remove it"`
+	ScheduledDate *string   `json:"scheduledDate,omitempty" jsonschema:"New scheduled date: YYYY-MM-DD, a relative date, or empty to remove it"`
+	StartDate     *string   `json:"startDate,omitempty" jsonschema:"New start date: YYYY-MM-DD, a relative date, or empty to remove it"`
+	Priority      *string   `json:"priority,omitempty" jsonschema:"New priority: highest, high, medium, low or none"`
+	Tags          *[]string `json:"tags,omitempty" jsonschema:"New set of tags, replacing the existing ones"`
+	Status        *string   `json:"status,omitempty" jsonschema:"New status symbol, e.g. x for done, / for in progress, - for cancelled or a space for todo"`
+}
+
+type UpdateTaskOutput struct {
+	Task *Task `json:"task"`
+	// NextOccurrence is the new task inserted above a completed recurring task
+	NextOccurrence *Task `json:"nextOccurrence,omitempty"`
+	// Warnings lists what the edit couldn't do, as for toggle_task
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (s *taskServer) updateTask(_ context.Context, _ *mcp.CallToolRequest, input UpdateTaskInput) (
+	*mcp.CallToolResult,
+	UpdateTaskOutput,
+	error,
+) {
+	edited, err := s.editTaskLine("update", input.ID, input.ExpectedDescription,
+		func(line string, day time.Time, settings *VaultSettings) (string, error) {
+			l, _ := splitTaskLine(line)
+			if err := applyTaskUpdate(l, input, day, settings); err != nil {
+				return "", err
+			}
+
+			return l.String(), nil
+		})
+	if err != nil {
+		return errorResult(err.Error()), UpdateTaskOutput{}, nil
+	}
+
+	return nil, UpdateTaskOutput{Task: edited.task, NextOccurrence: edited.next, Warnings: edited.warnings}, nil
+}
+
+// applyTaskUpdate applies the requested field changes to a task line
+//
+//nolint:gocyclo // one branch per updatable field
+func applyTaskUpdate(l *taskLine, input UpdateTaskInput, day time.Time, settings *VaultSettings) error {
+	if input.Description != nil {
+		description := strings.TrimSpace(*input.Description)
+		if description == "" || strings.ContainsAny(description, "\r\n") {
+			return errors.New("description must be a single non-empty line")
+		}
+
+		l.setDescription(description)
+
+		// the global filter may have been part of the old description (and
+		// left out of the description the client saw)
+		if !settings.isTask(l.content) {
+			l.setDescription(settings.withGlobalFilter(description))
+		}
+	}
+
+	for _, field := range []struct {
+		value *string
+		re    *regexp.Regexp
+		emoji string
+		name  string
+	}{
+		{input.StartDate, startDateRegex, "🛫", "start date"},
+		{input.ScheduledDate, scheduledDateRegex, "⏳", "scheduled date"},
+		{input.DueDate, dueDateRegex, "📅", "due date"},
+	} {
+		if field.value == nil {
+			continue
+		}
+
+		date := ""
+
+		if expr := strings.TrimSpace(*field.value); expr != "" {
+			var err error
+
+			date, err = resolveDate(expr, day)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", field.name, err)
+			}
+		}
+
+		l.setDate(field.re, field.emoji, date)
+	}
+
+	if input.Priority != nil {
+		priority, err := parsePriorityName(*input.Priority)
+		if err != nil {
+			return err
+		}
+
+		l.setPriority(priority)
+	}
+
+	if input.Tags != nil {
+		tags, err := normalizeTags(*input.Tags)
+		if err != nil {
+			return err
+		}
+
+		l.setTags(settings.withGlobalFilterTag(tags))
+	}
+
+	if input.Status != nil {
+		if utf8.RuneCountInString(*input.Status) != 1 {
+			return fmt.Errorf("invalid status %q: must be a single character", *input.Status)
+		}
+
+		l.setStatus(settings.statuses(), *input.Status, settings.doneDate(day.Format(dateLayout)))
+	}
+
+	if !settings.isTask(l.content) {
+		return fmt.Errorf("the edit would remove the global filter %q, so the line would no longer be a task", settings.GlobalFilter)
+	}
+
+	return nil
+}
+
+// normalizeTags strips leading #s from tags and validates them
+func normalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if !tagNameRegex.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+
+		out = append(out, tag)
+	}
+
+	return out, nil
+}
+
+// parseTaskID splits a task id of the form "path/to/file.md:12"
+func parseTaskID(id string) (string, int, error) {
+	i := strings.LastIndex(id, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid task id %q: expected path:line", id)
+	}
+
+	lineNumber, err := strconv.Atoi(id[i+1:])
+	if err != nil || lineNumber < 1 {
+		return "", 0, fmt.Errorf("invalid task id %q: bad line number", id)
+	}
+
+	return id[:i], lineNumber, nil
+}
+
+// resolveVaultFile maps a path relative to one of the configured roots, or
+// an absolute path such as those in the ids query_tasks returns, to the file's
+// path under that root. Paths are compared once symlinks are resolved, so the
+// root may be spelled either way, and files that resolve outside every root are
+// refused. It returns the file path, with any symlinks below the root
+// resolved, and the root it was found in.
+func (s *taskServer) resolveVaultFile(relPath string) (string, string, error) {
+	for _, root := range s.roots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+
+		resolvedRoot, err := realPath(absRoot)
+		if err != nil {
+			continue
+		}
+
+		path := relPath
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(absRoot, relPath)
+		}
+
+		resolved, err := realPath(path)
+		if err != nil || !isWithin(resolvedRoot, resolved) {
+			continue
+		}
+
+		if rel, err := filepath.Rel(resolvedRoot, resolved); err == nil {
+			return filepath.Join(absRoot, rel), absRoot, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("file %q not found in any configured root", relPath)
+}
+
+// realPath returns the absolute path of path with all symlinks resolved
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.EvalSymlinks(abs)
+}
+
+// isWithin reports whether path is root or inside it, lexically
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkTaskLine verifies that line is still a task with the given
+// description
+func checkTaskLine(line, description string, settings *VaultSettings) error {
+	task := ParseTaskWithSettings(line, "", 0, settings)
+	if task == nil {
+		return fmt.Errorf("%w: line is no longer a task", errTaskChanged)
+	}
+
+	if task.Description != description {
+		return fmt.Errorf("%w: description is now %q", errTaskChanged, task.Description)
+	}
+
+	return nil
+}
+
+// toggleTaskLine moves a task line to its next status in registry, adding a
+// done date (unless doneDate is empty) when it becomes done and removing it
+// when it stops being done. Everything else on the line is preserved.
+func toggleTaskLine(line, doneDate string, registry *StatusRegistry) string {
+	l, ok := splitTaskLine(line)
+	if !ok {
+		return line
+	}
+
+	l.setStatus(registry, registry.Lookup(l.symbol).NextSymbol, doneDate)
+
+	return l.String()
+}
+
+// withNextOccurrence returns the lines that replace before once it has been
+// edited into after. If the edit completed a recurring task, the next
+// occurrence is inserted above the completed task, as the Tasks plugin does.
+// When the next occurrence can't be created (e.g. the rule isn't supported),
+// the task is completed anyway, and the returned warnings say why it didn't
+// recur.
+func withNextOccurrence(before, after string, day time.Time, settings *VaultSettings) ([]string, []string) {
+	registry := settings.statuses()
+
+	was, _ := splitTaskLine(before)
+	now, _ := splitTaskLine(after)
+
+	task := ParseTaskWithSettings(after, "", 0, settings)
+	if task == nil || task.Recurrence == "" || registry.Lookup(was.symbol).Type == StatusTypeDone ||
+		registry.Lookup(now.symbol).Type != StatusTypeDone {
+		return []string{after}, nil
+	}
+
+	next, err := nextOccurrenceLine(after, day)
+	if err != nil {
+		return []string{after}, []string{fmt.Sprintf("the next occurrence of %q was not created: %v", task.Recurrence, err)}
+	}
+
+	return []string{next, after}, nil
+}
+
+// parseRewrittenTasks parses the lines written in place of the task at
+// lineNumber, returning the edited task and the next occurrence, if any
+func parseRewrittenTasks(lines []string, path, root string, lineNumber int, settings *VaultSettings) (*Task, *Task) {
+	last := len(lines) - 1
+	task := parseTaskAt(lines[last], path, root, lineNumber+last, settings)
+
+	if last == 0 {
+		return task, nil
+	}
+
+	return task, parseTaskAt(lines[0], path, root, lineNumber, settings)
+}
+
+// parseTaskAt parses line as a task in the file at path, with the same id as
+// query_tasks gives it and the file path made relative to root, as the
+// scanner does
+func parseTaskAt(line, path, root string, lineNumber int, settings *VaultSettings) *Task {
+	task := ParseTaskWithSettings(line, path, lineNumber, settings)
+	if task == nil {
+		return nil
+	}
+
+	if relPath, err := filepath.Rel(root, path); err == nil {
+		task.FilePath = relPath
+	}
+
+	return task
+}
+
+// rewriteLine replaces the given (1-based) line of a file with the lines
+// returned by edit, leaving every other byte of the file untouched. The line
+// passed to edit excludes its line ending, which is preserved and reused for
+// any added lines. The file is replaced atomically, and the edit is refused if
+// the file changes while it is being edited.
+func rewriteLine(path string, lineNumber int, edit func(line string) ([]string, error)) error {
+	before, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lineNumber > len(lines) || (lineNumber == len(lines) && lines[lineNumber-1] == "") {
+		return fmt.Errorf("%w: line %d is past the end of the file", errTaskChanged, lineNumber)
+	}
+
+	line := lines[lineNumber-1]
+	body := strings.TrimRight(line, "\r\n")
+
+	newBodies, err := edit(body)
+	if err != nil {
+		return err
+	}
+
+	eol := line[len(body):]
+	if eol == "" && len(newBodies) > 1 {
+		// the last line of the file is unterminated; reuse the file's endings
+		eol = "\n"
+		if strings.Contains(string(data), "\r\n") {
+			eol = "\r\n"
+		}
+	}
+
+	lines[lineNumber-1] = strings.Join(newBodies, eol) + line[len(body):]
+
+	return writeFileIfUnchanged(path, before, []byte(strings.Join(lines, "")))
+}
+
+// writeFileIfUnchanged atomically replaces the file at path with data, unless
+// its size or modification time differ from before
+func writeFileIfUnchanged(path string, before os.FileInfo, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), before.Mode().Perm()); err != nil {
+		return err
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) {
+		return fmt.Errorf("%w: file was modified during the edit", errTaskChanged)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testServer(t *testing.T, roots ...string) *taskServer {
+	t.Helper()
+
+	return &taskServer{
+		queryOptions: QueryOptions{
+			Now:      func() time.Time { return time.Date(2026, time.October, 16, 9, 0, 0, 0, time.UTC) },
+			Location: time.UTC,
+		},
+		roots: roots,
+	}
+}
+
+func TestParseTaskID(t *testing.T) {
+	path, line, err := parseTaskID("notes/todo.md:12")
+	require.NoError(t, err)
+	assert.Equal(t, "notes/todo.md", path)
+	assert.Equal(t, 12, line)
+
+	path, line, err = parseTaskID("C:/vault/todo.md:3")
+	require.NoError(t, err)
+	assert.Equal(t, "C:/vault/todo.md", path)
+	assert.Equal(t, 3, line)
+
+	for _, id := range []string{"todo.md", "todo.md:", "todo.md:0", "todo.md:abc", ":3"} {
+		_, _, err = parseTaskID(id)
+		require.Error(t, err, id)
+	}
+}
+
+func TestToggleTaskLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "todo becomes done",
+			line: "- [ ] Buy milk #shopping 📅 2026-10-20",
+			want: "- [x] Buy milk #shopping 📅 2026-10-20 ✅ 2026-10-16",
+		},
+		{
+			name: "done becomes todo",
+			line: "  - [x] Buy milk ✅ 2026-10-01 📅 2026-10-20",
+			want: "  - [ ] Buy milk 📅 2026-10-20",
+		},
+		{
+			name: "in progress becomes done",
+			line: "- [/] Write report",
+			want: "- [x] Write report ✅ 2026-10-16",
+		},
+		{
+			name: "done date goes before block link",
+			line: "- [ ] Linked task ^abc-123",
+			want: "- [x] Linked task ✅ 2026-10-16 ^abc-123",
+		},
+		{
+			name: "trailing whitespace is preserved",
+			line: "- [ ] Spacey task  ",
+			want: "- [x] Spacey task ✅ 2026-10-16  ",
+		},
+		{
+			name: "cancelled becomes todo",
+			line: "- [-] Dropped task",
+			want: "- [ ] Dropped task",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, toggleTaskLine(tt.line, "2026-10-16", nil))
+		})
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "notes", "todo.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+	require.NoError(t, os.WriteFile(file, []byte("# Tasks\r\n\r\n- [ ] Buy milk #shopping\r\n- [ ] Other task\r\n"), 0o600))
+
+	s := testServer(t, tmpDir)
+
+	res, out, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "notes/todo.md:3", Description: "Buy milk"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.NotNil(t, out.Task)
+	assert.Equal(t, file+":3", out.Task.ID)
+	assert.Equal(t, filepath.Join("notes", "todo.md"), out.Task.FilePath)
+	assert.Equal(t, "complete", out.Task.Status)
+	assert.Equal(t, "2026-10-16", out.Task.DoneDate)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "# Tasks\r\n\r\n- [x] Buy milk #shopping ✅ 2026-10-16\r\n- [ ] Other task\r\n", string(data))
+
+	t.Run("refuses when description changed", func(t *testing.T) {
+		res, _, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "notes/todo.md:4", Description: "Something else"})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("refuses when line is not a task", func(t *testing.T) {
+		res, _, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "notes/todo.md:1", Description: "Tasks"})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("refuses paths outside the roots", func(t *testing.T) {
+		res, _, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "../todo.md:3", Description: "Buy milk"})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("refuses files symlinked from outside the roots", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "outside.md")
+		require.NoError(t, os.WriteFile(outside, []byte("- [ ] Outside\n"), 0o600))
+		require.NoError(t, os.Symlink(outside, filepath.Join(tmpDir, "link.md")))
+
+		res, _, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "link.md:1", Description: "Outside"})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("refuses lines past the end of the file", func(t *testing.T) {
+		res, _, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "notes/todo.md:5", Description: "Other task"})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("refuses without a description", func(t *testing.T) {
+		res, _, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "notes/todo.md:4"})
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.True(t, res.IsError)
+	})
+
+	data, err = os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "# Tasks\r\n\r\n- [x] Buy milk #shopping ✅ 2026-10-16\r\n- [ ] Other task\r\n", string(data))
+}
+
+func TestToggleTaskSymlinkedRoot(t *testing.T) {
+	base := t.TempDir()
+	vault := filepath.Join(base, "vault")
+	link := filepath.Join(base, "link")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(vault, "notes"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(vault, "notes", "todo.md"), []byte("- [ ] Buy milk\n"), 0o600))
+	require.NoError(t, os.Symlink(vault, link))
+
+	s := testServer(t, link)
+
+	res, queried, err := s.queryTasks(t.Context(), nil, QueryTasksInput{Query: "not done", RootDirs: []string{link}})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.Len(t, queried.Tasks, 1)
+
+	res, out, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: queried.Tasks[0].ID, Description: "Buy milk"})
+	require.NoError(t, err)
+	require.Nil(t, res, "%v", res)
+	assert.Equal(t, filepath.Join("notes", "todo.md"), out.Task.FilePath)
+	assert.Equal(t, "complete", out.Task.Status)
+
+	data, err := os.ReadFile(filepath.Join(vault, "notes", "todo.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "- [x] Buy milk ✅ 2026-10-16\n", string(data))
+}
+
+//nolint:funlen // comprehensive test cases
+func TestToggleRecurringTask(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "todo.md")
+	require.NoError(t, os.WriteFile(file, []byte("# Chores\n- [ ] Bins 🔁 every week on Tuesday 📅 2026-10-13"), 0o600))
+
+	s := testServer(t, tmpDir)
+
+	res, out, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "todo.md:2", Description: "Bins"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.NotNil(t, out.Task)
+	require.NotNil(t, out.NextOccurrence)
+	assert.Equal(t, file+":3", out.Task.ID)
+	assert.Equal(t, "complete", out.Task.Status)
+	assert.Equal(t, file+":2", out.NextOccurrence.ID)
+	assert.Equal(t, "2026-10-20", out.NextOccurrence.DueDate)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "# Chores\n- [ ] Bins 🔁 every week on Tuesday 📅 2026-10-20\n"+
+		"- [x] Bins 🔁 every week on Tuesday 📅 2026-10-13 ✅ 2026-10-16", string(data))
+
+	t.Run("un-completing does not recur", func(t *testing.T) {
+		res, out, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: file + ":3", Description: "Bins"})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		assert.Nil(t, out.NextOccurrence)
+		assert.Equal(t, "incomplete", out.Task.Status)
+	})
+
+	t.Run("rules that can't recur still complete", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte("- [ ] New year 🔁 every year on January 1st 📅 2026-01-01\n"+
+			"- [ ] Rent 🔁 every 12 months on the 30th 📅 2026-02-10\n"), 0o600))
+
+		res, out, err := s.toggleTask(t.Context(), nil, ToggleTaskInput{ID: "todo.md:1", Description: "New year"})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		assert.Nil(t, out.NextOccurrence)
+		assert.Equal(t, "complete", out.Task.Status)
+		require.Len(t, out.Warnings, 1)
+		assert.Contains(t, out.Warnings[0], "every year on January 1st")
+
+		done := "x"
+
+		res, updated, err := s.updateTask(t.Context(), nil, UpdateTaskInput{ID: "todo.md:2", ExpectedDescription: "Rent", Status: &done})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		assert.Nil(t, updated.NextOccurrence)
+		assert.Equal(t, "complete", updated.Task.Status)
+		require.Len(t, updated.Warnings, 1)
+		assert.Contains(t, updated.Warnings[0], "no month matches")
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, "- [x] New year 🔁 every year on January 1st 📅 2026-01-01 ✅ 2026-10-16\n"+
+			"- [x] Rent 🔁 every 12 months on the 30th 📅 2026-02-10 ✅ 2026-10-16\n", string(data))
+	})
+}
+
+func TestInsertTaskLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		heading  string
+		want     string
+		wantLine int
+	}{
+		{
+			name:     "empty file",
+			content:  "",
+			want:     "- [ ] New\n",
+			wantLine: 1,
+		},
+		{
+			name:     "append at end",
+			content:  "# Notes\n\n- [ ] Old\n",
+			want:     "# Notes\n\n- [ ] Old\n- [ ] New\n",
+			wantLine: 4,
+		},
+		{
+			name:     "append to file without trailing newline",
+			content:  "# Notes\n- [ ] Old",
+			want:     "# Notes\n- [ ] Old\n- [ ] New\n",
+			wantLine: 3,
+		},
+		{
+			name:     "under heading before next section",
+			content:  "# Day\n\n## Tasks\n\n- [ ] Old\n\n## Notes\n\nText\n",
+			heading:  "Tasks",
+			want:     "# Day\n\n## Tasks\n\n- [ ] Old\n- [ ] New\n\n## Notes\n\nText\n",
+			wantLine: 6,
+		},
+		{
+			name:     "ignores headings in code blocks",
+			content:  "## Tasks\n- [ ] Old\n```sh\n# Tasks\n## echo\n```\n## Notes\n",
+			heading:  "Tasks",
+			want:     "## Tasks\n- [ ] Old\n```sh\n# Tasks\n## echo\n```\n- [ ] New\n## Notes\n",
+			wantLine: 7,
+		},
+		{
+			name:     "under heading with subsections",
+			content:  "## Tasks\n- [ ] Old\n### Sub\n- [ ] Sub task\n## Notes\n",
+			heading:  "Tasks",
+			want:     "## Tasks\n- [ ] Old\n### Sub\n- [ ] Sub task\n- [ ] New\n## Notes\n",
+			wantLine: 5,
+		},
+		{
+			name:     "under empty heading at end of file",
+			content:  "## Tasks\n",
+			heading:  "Tasks",
+			want:     "## Tasks\n- [ ] New\n",
+			wantLine: 2,
+		},
+		{
+			name:     "missing heading is appended",
+			content:  "# Notes\nText\n",
+			heading:  "Tasks",
+			want:     "# Notes\nText\n\n## Tasks\n- [ ] New\n",
+			wantLine: 5,
+		},
+		{
+			name:     "crlf line endings are reused",
+			content:  "## Tasks\r\n- [ ] Old\r\n",
+			heading:  "Tasks",
+			want:     "## Tasks\r\n- [ ] Old\r\n- [ ] New\r\n",
+			wantLine: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, line := insertTaskLine(tt.content, tt.heading, "- [ ] New")
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantLine, line)
+		})
+	}
+}
+
+func TestCreateTask(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := testServer(t, tmpDir)
+
+	res, out, err := s.createTask(t.Context(), nil, CreateTaskInput{
+		Description: "Call the plumber",
+		Path:        "Projects/house",
+		Heading:     "Tasks",
+		DueDate:     "tomorrow",
+		StartDate:   "2026-10-16",
+		Priority:    "high",
+		Tags:        []string{"#home", "urgent", "work/clientA", "été"},
+	})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.NotNil(t, out.Task)
+	assert.Equal(t, filepath.Join(tmpDir, "Projects", "house.md")+":2", out.Task.ID)
+	assert.Equal(t, filepath.Join("Projects", "house.md"), out.Task.FilePath)
+	assert.Equal(t, "Call the plumber", out.Task.Description)
+	assert.Equal(t, "2026-10-17", out.Task.DueDate)
+	assert.Equal(t, PriorityHigh, out.Task.Priority)
+	assert.Equal(t, []string{"home", "urgent", "work/clientA", "été"}, out.Task.Tags)
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "Projects", "house.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "## Tasks\n- [ ] Call the plumber #home #urgent #work/clientA #été ⏫ 🛫 2026-10-16 📅 2026-10-17\n", string(data))
+
+	t.Run("daily note", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".obsidian"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".obsidian", "daily-notes.json"),
+			[]byte(`{"folder":"Daily/","format":"YYYY/YYYY-MM-DD"}`), 0o600))
+
+		res, out, err := s.createTask(t.Context(), nil, CreateTaskInput{Description: "Stand-up", Daily: true})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		assert.Equal(t, filepath.Join(tmpDir, "Daily", "2026", "2026-10-16.md")+":1", out.Task.ID)
+	})
+
+	t.Run("refuses folders symlinked from outside the root", func(t *testing.T) {
+		outside := t.TempDir()
+		require.NoError(t, os.Symlink(outside, filepath.Join(tmpDir, "escape")))
+
+		for _, path := range []string{"escape/x.md", "escape/new/x.md"} {
+			res, _, err := s.createTask(t.Context(), nil, CreateTaskInput{Description: "x", Path: path})
+			require.NoError(t, err)
+			require.NotNil(t, res)
+			assert.True(t, res.IsError)
+		}
+
+		entries, err := os.ReadDir(outside)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("follows symlinks within the root", func(t *testing.T) {
+		require.NoError(t, os.Symlink(filepath.Join(tmpDir, "Projects"), filepath.Join(tmpDir, "current")))
+
+		res, _, err := s.createTask(t.Context(), nil, CreateTaskInput{Description: "Paint the shed", Path: "current/shed.md"})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		assert.FileExists(t, filepath.Join(tmpDir, "Projects", "shed.md"))
+	})
+
+	for name, input := range map[string]CreateTaskInput{
+		"missing description": {Path: "x.md"},
+		"missing path":        {Description: "x"},
+		"escaping path":       {Description: "x", Path: "../outside.md"},
+		"bad priority":        {Description: "x", Path: "x.md", Priority: "urgent"},
+		"bad date":            {Description: "x", Path: "x.md", DueDate: "someday"},
+		"date range":          {Description: "x", Path: "x.md", DueDate: "next week"},
+		"bad tag":             {Description: "x", Path: "x.md", Tags: []string{"two words"}},
+		"numeric tag":         {Description: "x", Path: "x.md", Tags: []string{"2024"}},
+		"multi-line":          {Description: "x\n- [ ] y", Path: "x.md"},
+		"unknown root":        {Description: "x", Path: "x.md", Root: "/elsewhere"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			res, _, err := s.createTask(t.Context(), nil, input)
+			require.NoError(t, err)
+			require.NotNil(t, res)
+			assert.True(t, res.IsError)
+		})
+	}
+}
+
+func TestUpdateTask(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "todo.md")
+	require.NoError(t, os.WriteFile(file, []byte("- [ ] Call Bob #phone 🔼 🔁 every week 📅 2026-10-01\n- [ ] Other\n"), 0o600))
+
+	s := testServer(t, tmpDir)
+
+	desc := "Call Alice"
+	due := "tomorrow"
+	prio := "high"
+	tags := []string{"phone", "#work"}
+	status := "/"
+
+	res, out, err := s.updateTask(t.Context(), nil, UpdateTaskInput{
+		ID:                  "todo.md:1",
+		ExpectedDescription: "Call Bob",
+		Description:         &desc,
+		DueDate:             &due,
+		Priority:            &prio,
+		Tags:                &tags,
+		Status:              &status,
+	})
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.NotNil(t, out.Task)
+	assert.Equal(t, "2026-10-17", out.Task.DueDate)
+	assert.Equal(t, StatusTypeInProgress, out.Task.StatusType)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "- [/] Call Alice #phone #work ⏫ 🔁 every week 📅 2026-10-17\n- [ ] Other\n", string(data))
+
+	t.Run("clearing a date", func(t *testing.T) {
+		empty := ""
+
+		res, out, err := s.updateTask(t.Context(), nil, UpdateTaskInput{ID: "todo.md:1", ExpectedDescription: "Call Alice", DueDate: &empty})
+		require.NoError(t, err)
+		require.Nil(t, res)
+		assert.Empty(t, out.Task.DueDate)
+	})
+
+	t.Run("invalid changes are refused", func(t *testing.T) {
+		bad := "someday"
+		twoChars := "xx"
+
+		for _, input := range []UpdateTaskInput{
+			{ID: "todo.md:1", ExpectedDescription: "Call Alice", DueDate: &bad},
+			{ID: "todo.md:1", ExpectedDescription: "Call Alice", Priority: &bad},
+			{ID: "todo.md:1", ExpectedDescription: "Call Alice", Status: &twoChars},
+			{ID: "todo.md:1", ExpectedDescription: "Call Bob", Priority: &prio},
+			{ID: "todo.md:1", Priority: &prio},
+		} {
+			res, _, err := s.updateTask(t.Context(), nil, input)
+			require.NoError(t, err)
+			require.NotNil(t, res)
+			assert.True(t, res.IsError)
+		}
+
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, "- [/] Call Alice #phone #work ⏫ 🔁 every week\n- [ ] Other\n", string(data))
+	})
+}